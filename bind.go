@@ -0,0 +1,237 @@
+package rte
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+)
+
+// ParamsSpec describes a route for which ExportParams generates a struct of its path variables.
+type ParamsSpec struct {
+	// Name is the route's name, e.g. "UsersShow"; the struct is named with the suffix "Params", e.g. "UsersShowParams"
+	Name string
+	// Path is the route's path, e.g. "/users/:id"; each of its variables must be named
+	Path string
+	// Kinds maps variable names to their Go types: "string", the default, "int64", "uint64", or "netip.Addr"
+	Kinds map[string]string
+}
+
+// maxPositionalVars is the greatest number of path variables a handler accepts as individual arguments
+const maxPositionalVars = 4
+
+// paramKinds are the supported ParamsSpec kinds and the Go expressions parsing a variable, v, into a value and an error
+var paramKinds = map[string]string{
+	"string":     "",
+	"int64":      "strconv.ParseInt(v, 10, 64)",
+	"uint64":     "parseUint(v)",
+	"netip.Addr": "netip.ParseAddr(v)",
+}
+
+// ExportParams generates the source of a Go package, named pkg, which lets handlers receive a route's path variables as
+// the fields of a struct rather than as positional arguments. For each spec, e.g.
+//
+//	rte.ParamsSpec{Name: "UsersShow", Path: "/users/:id", Kinds: map[string]string{"id": "int64"}}
+//
+// it declares a struct and a function adapting a handler taking the struct to one accepted by New:
+//
+//	type UsersShowParams struct {
+//		ID int64
+//	}
+//
+//	func UsersShowHandler(
+//		h func(http.ResponseWriter, *http.Request, UsersShowParams),
+//		onErr func(http.ResponseWriter, *http.Request, *rte.ParseError),
+//	) func(w http.ResponseWriter, r *http.Request, id string)
+//
+// Routes with more than four variables are adapted to handlers taking them as an array, e.g. pVars [5]string. A
+// variable which can't be parsed is reported to onErr -- e.g. a Table's OnParseError -- as it would be for a typed
+// handler; if onErr is nil, the request is answered with a 400. Routes must use the default separator.
+func ExportParams(pkg string, specs ...ParamsSpec) (string, error) {
+	type field struct {
+		name, param, kind string
+	}
+
+	var (
+		b      strings.Builder
+		kinds  = make(map[string]bool)
+		routes = make(map[string]bool)
+	)
+	for _, s := range specs {
+		if !token.IsIdentifier(s.Name) {
+			return "", fmt.Errorf("route %q: invalid name %q", s.Path, s.Name)
+		}
+		if routes[s.Name] {
+			return "", fmt.Errorf("route %q: duplicate name %q", s.Path, s.Name)
+		}
+		routes[s.Name] = true
+
+		names, ok := pathVarNames(s.Path)
+		if !ok {
+			return "", fmt.Errorf("route %q: invalid path", s.Path)
+		}
+		for name, kind := range s.Kinds {
+			if _, ok := paramKinds[kind]; !ok {
+				return "", fmt.Errorf("route %q: variable %q has unsupported kind %q", s.Path, name, kind)
+			}
+			var found bool
+			for _, n := range names {
+				found = found || n == name
+			}
+			if !found {
+				return "", fmt.Errorf("route %q: no variable %q", s.Path, name)
+			}
+		}
+
+		var (
+			fields     []field
+			fieldNames []string
+			hasTyped   bool
+			// reserved for the generated function's own identifiers, and those of the file, which mustn't be shadowed
+			paramNames = []string{
+				"w", "r", "h", "p", "onErr", "err", "pVars",
+				"errors", "http", "netip", "strconv", "rte", "parseError", "parseUint",
+			}
+		)
+		for _, name := range names {
+			if name == "" {
+				return "", fmt.Errorf("route %q: unnamed variable", s.Path)
+			}
+			kind := s.Kinds[name]
+			if kind == "" {
+				kind = "string"
+			}
+			kinds[kind] = true
+			hasTyped = hasTyped || kind != "string"
+
+			f := field{name: exportedName(name, fieldNames), param: paramName(name, paramNames), kind: kind}
+			fields = append(fields, f)
+			fieldNames = append(fieldNames, f.name)
+			paramNames = append(paramNames, f.param)
+		}
+
+		_, _ = fmt.Fprintf(&b, "\n// %vParams holds the path variables of the route %q\n", s.Name, s.Path)
+		_, _ = fmt.Fprintf(&b, "type %vParams struct {\n", s.Name)
+		for _, f := range fields {
+			_, _ = fmt.Fprintf(&b, "\t%v %v\n", f.name, f.kind)
+		}
+		b.WriteString("}\n")
+
+		sig := "w http.ResponseWriter, r *http.Request"
+		switch {
+		case len(fields) > maxPositionalVars:
+			// handlers only accept more variables as an array
+			sig += fmt.Sprintf(", pVars [%d]string", len(fields))
+			for i := range fields {
+				fields[i].param = fmt.Sprintf("pVars[%d]", i)
+			}
+		case len(fields) > 0:
+			sig += ", " + strings.Join(paramNames[len(paramNames)-len(fields):], ", ") + " string"
+		}
+		_, _ = fmt.Fprintf(&b, "\n// %vHandler adapts h to a handler for the route %q;\n"+
+			"// variables which can't be parsed are reported to onErr, or answered with a 400 if it's nil\n", s.Name, s.Path)
+		_, _ = fmt.Fprintf(&b, "func %vHandler(\n\th func(http.ResponseWriter, *http.Request, %vParams),\n", s.Name, s.Name)
+		b.WriteString("\tonErr func(http.ResponseWriter, *http.Request, *rte.ParseError),\n")
+		_, _ = fmt.Fprintf(&b, ") func(%v) {\n\treturn func(%v) {\n", sig, sig)
+		_, _ = fmt.Fprintf(&b, "\t\tvar p %vParams\n", s.Name)
+		if hasTyped {
+			b.WriteString("\t\tvar err error\n")
+		}
+		for i, f := range fields {
+			if f.kind == "string" {
+				_, _ = fmt.Fprintf(&b, "\t\tp.%v = %v\n", f.name, f.param)
+				continue
+			}
+			parse := strings.Replace(paramKinds[f.kind], "(v", "("+f.param, 1)
+			_, _ = fmt.Fprintf(&b, "\t\tif p.%v, err = %v; err != nil {\n", f.name, parse)
+			_, _ = fmt.Fprintf(&b, "\t\t\tparseError(w, r, onErr, &rte.ParseError{Index: %d, Value: %v, Kind: %q, Err: err})\n",
+				i, f.param, f.kind)
+			b.WriteString("\t\t\treturn\n\t\t}\n")
+		}
+		b.WriteString("\t\th(w, r, p)\n\t}\n}\n")
+	}
+
+	typed := kinds["int64"] || kinds["uint64"] || kinds["netip.Addr"]
+
+	var header strings.Builder
+	_, _ = fmt.Fprintf(&header, "// Code generated by rte.ExportParams. DO NOT EDIT.\n\npackage %v\n\nimport (\n", pkg)
+	if typed {
+		header.WriteString("\t\"errors\"\n")
+	}
+	header.WriteString("\t\"net/http\"\n")
+	if kinds["netip.Addr"] {
+		header.WriteString("\t\"net/netip\"\n")
+	}
+	if typed {
+		header.WriteString("\t\"strconv\"\n")
+	}
+	header.WriteString("\n\t\"github.com/jwilner/rte\"\n)\n")
+	if typed {
+		header.WriteString(`
+// parseError reports a path variable which can't be parsed, as a Table does for typed handlers
+func parseError(
+	w http.ResponseWriter, r *http.Request, onErr func(http.ResponseWriter, *http.Request, *rte.ParseError),
+	err *rte.ParseError,
+) {
+	var numErr *strconv.NumError
+	if errors.As(err.Err, &numErr) {
+		err.Err = numErr.Err
+	}
+	if onErr != nil {
+		onErr(w, r, err)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+`)
+	}
+	if kinds["uint64"] {
+		header.WriteString(`
+// parseUint parses a base 10 uint64, reporting negative values, but not "-0", as rte.ErrNegative
+func parseUint(s string) (uint64, error) {
+	if len(s) > 1 && s[0] == '-' {
+		if v, err := strconv.ParseUint(s[1:], 10, 64); (err == nil && v != 0) || errors.Is(err, strconv.ErrRange) {
+			return 0, rte.ErrNegative
+		}
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+`)
+	}
+
+	src, err := format.Source([]byte(header.String() + b.String()))
+	if err != nil {
+		// the generated source is syntactically valid for any identifiers which pass validation
+		panic(fmt.Sprintf("rte.ExportParams: failed formatting source: %v", err))
+	}
+	return string(src), nil
+}
+
+// pathVarNames returns the names of a path's variables, including a format variable, or false if the path is invalid
+func pathVarNames(path string) ([]string, bool) {
+	if path == "" || path[0] != '/' {
+		return nil, false
+	}
+	trimmed, hasFormat, ok := cutFormat(path, '/')
+	if !ok {
+		return nil, false
+	}
+	p, ok := scanPath(trimmed, '/')
+	if !ok {
+		return nil, false
+	}
+	if hasFormat {
+		p.names = append(p.names, path[len(trimmed)+2:])
+	}
+	return p.names, true
+}
+
+// exportedName converts a path variable name, e.g. "user_id", to a unique exported Go identifier, e.g. "UserID"
+func exportedName(varName string, taken []string) string {
+	name := camelCase(varName, true)
+	if !token.IsIdentifier(name) || !token.IsExported(name) {
+		// e.g. "1st"
+		name = "V" + name
+	}
+	return uniqueName(name, taken)
+}
@@ -0,0 +1,193 @@
+package rte_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jwilner/rte"
+)
+
+func TestExportParams(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain unavailable")
+	}
+	repo, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := rte.ExportParams("main",
+		rte.ParamsSpec{Name: "UsersShow", Path: "/users/:id", Kinds: map[string]string{"id": "int64"}},
+		rte.ParamsSpec{
+			Name:  "PostsShow",
+			Path:  "/users/:user_id/posts/:post_id.:format",
+			Kinds: map[string]string{"user_id": "uint64", "post_id": "int64"},
+		},
+		rte.ParamsSpec{Name: "Hosts", Path: "/hosts/:ip/:type/:w", Kinds: map[string]string{"ip": "netip.Addr"}},
+		rte.ParamsSpec{Name: "Health", Path: "/health"},
+		rte.ParamsSpec{
+			Name:  "Shadow",
+			Path:  "/x/:rte/:strconv/:http/:parse_uint",
+			Kinds: map[string]string{"rte": "int64", "strconv": "int64", "http": "uint64", "parse_uint": "uint64"},
+		},
+		rte.ParamsSpec{
+			Name:  "Shadow2",
+			Path:  "/y/:errors/:netip/:parse_error/:p_vars",
+			Kinds: map[string]string{"netip": "netip.Addr", "parse_error": "int64"},
+		},
+		rte.ParamsSpec{
+			Name:  "Many",
+			Path:  "/m/:a/:b/:c/:d/:e.:format",
+			Kinds: map[string]string{"a": "int64", "e": "uint64"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"go.mod": "module client\n\ngo 1.19\n\nrequire github.com/jwilner/rte v0.0.0\n\n" +
+			"replace github.com/jwilner/rte => " + repo + "\n",
+		"params.go": src,
+		"main.go": `package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/jwilner/rte"
+)
+
+func main() {
+	onErr := func(w http.ResponseWriter, r *http.Request, err *rte.ParseError) {
+		fmt.Printf("onErr %v %q %v %v\n", err.Index, err.Value, err.Kind, err.Err)
+	}
+	tbl := rte.Must(rte.Routes(
+		"GET /users/:id", UsersShowHandler(func(w http.ResponseWriter, r *http.Request, p UsersShowParams) {
+			fmt.Printf("%#v\n", p)
+		}, nil),
+		"GET /users/:user_id/posts/:post_id.:format", PostsShowHandler(
+			func(w http.ResponseWriter, r *http.Request, p PostsShowParams) {
+				fmt.Printf("%#v\n", p)
+			},
+			onErr,
+		),
+		"GET /hosts/:ip/:type/:w", HostsHandler(func(w http.ResponseWriter, r *http.Request, p HostsParams) {
+			fmt.Println(p.IP, p.Type, p.W)
+		}, onErr),
+		"GET /health", HealthHandler(func(w http.ResponseWriter, r *http.Request, p HealthParams) {
+			fmt.Printf("%#v\n", p)
+		}, nil),
+		"GET /x/:rte/:strconv/:http/:parse_uint", ShadowHandler(
+			func(w http.ResponseWriter, r *http.Request, p ShadowParams) {
+				fmt.Println(p.Rte, p.Strconv, p.HTTP, p.ParseUint)
+			},
+			onErr,
+		),
+		"GET /y/:errors/:netip/:parse_error/:p_vars", Shadow2Handler(
+			func(w http.ResponseWriter, r *http.Request, p Shadow2Params) {
+				fmt.Println(p.Errors, p.Netip, p.ParseError, p.PVars)
+			},
+			onErr,
+		),
+		"GET /m/:a/:b/:c/:d/:e.:format", ManyHandler(func(w http.ResponseWriter, r *http.Request, p ManyParams) {
+			fmt.Printf("%#v\n", p)
+		}, onErr),
+	))
+
+	for _, path := range []string{
+		"/users/123",
+		"/users/abc",
+		"/users/5/posts/-7.json",
+		"/users/5/posts/7",
+		"/users/-5/posts/7.json",
+		"/users/-0/posts/7.json",
+		"/hosts/10.0.0.1/a/b",
+		"/hosts/nope/a/b",
+		"/health",
+		"/x/1/2/3/4",
+		"/x/1/two/3/4",
+		"/y/e/::1/5/pv",
+		"/m/1/b/c/d/2.json",
+		"/m/1/b/c/d/-2.json",
+	} {
+		w := httptest.NewRecorder()
+		tbl.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			fmt.Println(w.Code)
+		}
+	}
+}
+`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goBin, "run", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("generated source failed: %v\n%s\n%v", err, out, src)
+	}
+
+	want := `main.UsersShowParams{ID:123}
+400
+main.PostsShowParams{UserID:0x5, PostID:-7, Format:"json"}
+main.PostsShowParams{UserID:0x5, PostID:7, Format:""}
+onErr 0 "-5" uint64 value cannot be negative
+onErr 0 "-0" uint64 invalid syntax
+10.0.0.1 a b
+onErr 0 "nope" netip.Addr ParseAddr("nope"): unable to parse IP
+main.HealthParams{}
+1 2 3 4
+onErr 1 "two" int64 invalid syntax
+e ::1 5 pv
+main.ManyParams{A:1, B:"b", C:"c", D:"d", E:0x2, Format:"json"}
+onErr 4 "-2" uint64 value cannot be negative
+`
+	if string(out) != want {
+		t.Fatalf("want:\n%v\ngot:\n%s", want, out)
+	}
+}
+
+func TestExportParamsErrors(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		spec rte.ParamsSpec
+		want string
+	}{
+		{"invalid name", rte.ParamsSpec{Name: "a-b", Path: "/foo"}, `route "/foo": invalid name "a-b"`},
+		{"invalid path", rte.ParamsSpec{Name: "Foo", Path: "/foo/a:b"}, `route "/foo/a:b": invalid path`},
+		{"unnamed variable", rte.ParamsSpec{Name: "Foo", Path: "/foo/:"}, `route "/foo/:": unnamed variable`},
+		{
+			"unknown variable",
+			rte.ParamsSpec{Name: "Foo", Path: "/foo/:id", Kinds: map[string]string{"ID": "int64"}},
+			`route "/foo/:id": no variable "ID"`,
+		},
+		{
+			"unsupported kind",
+			rte.ParamsSpec{Name: "Foo", Path: "/foo/:id", Kinds: map[string]string{"id": "float64"}},
+			`route "/foo/:id": variable "id" has unsupported kind "float64"`,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := rte.ExportParams("main", c.spec)
+			if err == nil || err.Error() != c.want {
+				t.Fatalf("want %q but got %v", c.want, err)
+			}
+		})
+	}
+
+	spec := rte.ParamsSpec{Name: "Foo", Path: "/foo"}
+	if _, err := rte.ExportParams("main", spec, spec); err == nil || !strings.Contains(err.Error(), "duplicate name") {
+		t.Fatalf("want duplicate name error but got %v", err)
+	}
+}
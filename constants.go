@@ -0,0 +1,181 @@
+package rte
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// ExportConstants generates the source of a Go package, named pkg, which permits clients to refer to the table's named
+// routes without string literals. For each named route (see Route.Name), e.g.
+//
+//	rte.Route{Name: "GetPost", Method: "GET", Path: "/users/:user_id/posts/:post_id", ...}
+//
+// it declares a constant holding the path template and a function building a path from variables:
+//
+//	const GetPost Template = "/users/:user_id/posts/:post_id"
+//
+//	func GetPostPath(userID, postID string) string
+//
+// Variables are escaped with url.PathEscape, and an empty format variable (e.g. "/posts/:id.:format") is omitted along
+// with its '.'. The generated identifiers are exported only if the route names are. It returns an error if a route's
+// name collides with another generated identifier: the Template type, the url import, the withFormat helper, init,
+// main if pkg is "main", or the function of a route named with the suffix "Path", e.g. "Get" and "GetPath".
+func (t *Table) ExportConstants(pkg string) (string, error) {
+	var named []patternHandler
+	for _, h := range t.handlers() {
+		if h.handler.Route.Name != "" {
+			named = append(named, h)
+		}
+	}
+	sort.Slice(named, func(i, j int) bool {
+		return named[i].handler.Idx < named[j].handler.Idx
+	})
+
+	names := make(map[string]Route, len(named))
+	for _, h := range named {
+		names[h.handler.Route.Name] = h.handler.Route
+	}
+	for _, h := range named {
+		r := h.handler.Route
+		if reservedConstants[r.Name] || (pkg == "main" && r.Name == "main") {
+			return "", fmt.Errorf("route %q: name %q is reserved", r.String(), r.Name)
+		}
+		if other, ok := names[r.Name+"Path"]; ok {
+			return "", fmt.Errorf("route %q: function %vPath collides with route %q", r.String(), r.Name, other.String())
+		}
+	}
+
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "// Code generated by rte.ExportConstants. DO NOT EDIT.\n\npackage %v\n\n", pkg)
+
+	var hasVars, hasFormat bool
+	for _, h := range named {
+		if strings.Contains(h.handler.Route.Path, ":") {
+			hasVars = true
+		}
+		hasFormat = hasFormat || h.handler.format
+	}
+	if hasVars {
+		b.WriteString("import \"net/url\"\n\n")
+	}
+
+	b.WriteString("// Template is the path template of a route\ntype Template string\n")
+	if hasFormat {
+		b.WriteString("\n// withFormat appends the format to the path, if it's provided\n")
+		b.WriteString("func withFormat(path, format string) string {\n")
+		b.WriteString("\tif format == \"\" {\n\t\treturn path\n\t}\n\treturn path + \".\" + url.PathEscape(format)\n}\n")
+	}
+
+	for _, h := range named {
+		r := h.handler.Route
+
+		var (
+			params []string
+			parts  []string
+			static strings.Builder
+			// the parameters mustn't shadow the helper
+			taken = []string{"withFormat"}
+		)
+		path, names := r.Path, h.handler.varNames
+		if h.handler.format {
+			path = path[:strings.LastIndex(path, ".:")]
+		}
+		for i, seg := range strings.Split(path, string(t.sep)) {
+			if i > 0 {
+				static.WriteByte(t.sep)
+			}
+			if !strings.HasPrefix(seg, ":") {
+				static.WriteString(seg)
+				continue
+			}
+			if static.Len() > 0 {
+				parts = append(parts, fmt.Sprintf("%q", static.String()))
+				static.Reset()
+			}
+			param := paramName(names[len(params)], taken)
+			params, taken = append(params, param), append(taken, param)
+			parts = append(parts, fmt.Sprintf("url.PathEscape(%v)", param))
+		}
+		if static.Len() > 0 {
+			parts = append(parts, fmt.Sprintf("%q", static.String()))
+		}
+
+		expr := strings.Join(parts, " + ")
+		if h.handler.format {
+			param := paramName(names[len(params)], taken)
+			params = append(params, param)
+			expr = fmt.Sprintf("withFormat(%v, %v)", expr, param)
+		}
+
+		var sig string
+		if len(params) > 0 {
+			sig = strings.Join(params, ", ") + " string"
+		}
+
+		_, _ = fmt.Fprintf(&b, "\n// %v is the path template of the route %q\n", r.Name, r.String())
+		_, _ = fmt.Fprintf(&b, "const %v Template = %q\n", r.Name, r.Path)
+		_, _ = fmt.Fprintf(&b, "\n// %vPath returns the path of the route %q with the provided variables\n", r.Name, r.String())
+		_, _ = fmt.Fprintf(&b, "func %vPath(%v) string {\n\treturn %v\n}\n", r.Name, sig, expr)
+	}
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		// the generated source is syntactically valid for any route names which are Go identifiers
+		panic(fmt.Sprintf("rte.ExportConstants: failed formatting source: %v", err))
+	}
+	return string(src), nil
+}
+
+// reservedConstants are the identifiers ExportConstants declares or imports, other than those of routes
+var reservedConstants = map[string]bool{"Template": true, "url": true, "withFormat": true, "init": true}
+
+// commonInitialisms are upper cased in parameter names, per Go convention
+var commonInitialisms = map[string]bool{"api": true, "http": true, "id": true, "ip": true, "uri": true, "url": true}
+
+// paramName converts a path variable name, e.g. "user_id", to a unique Go parameter name, e.g. "userID"
+func paramName(varName string, taken []string) string {
+	name := camelCase(varName, false)
+	if name == "" || !token.IsIdentifier(name) || token.IsKeyword(name) || name == "url" {
+		// e.g. "type", "1st", or the imported package
+		if name != "" {
+			name = strings.ToUpper(name[:1]) + name[1:]
+		}
+		name = "v" + name
+	}
+	return uniqueName(name, taken)
+}
+
+// camelCase joins the words of a path variable name, e.g. "user_id", into "userID", or "UserID" if exported
+func camelCase(varName string, exported bool) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(varName, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) {
+		switch {
+		case b.Len() == 0 && !exported:
+			b.WriteString(strings.ToLower(word))
+		case commonInitialisms[strings.ToLower(word)]:
+			b.WriteString(strings.ToUpper(word))
+		default:
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// uniqueName suffixes name with a number, if necessary, to distinguish it from those taken
+func uniqueName(name string, taken []string) string {
+	for unique, i := name, 1; ; i++ {
+		var found bool
+		for _, p := range taken {
+			found = found || p == unique
+		}
+		if !found {
+			return unique
+		}
+		unique = fmt.Sprintf("%v%d", name, i)
+	}
+}
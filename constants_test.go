@@ -0,0 +1,127 @@
+package rte_test
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jwilner/rte"
+)
+
+func TestExportConstants(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+
+	for _, c := range []struct {
+		name, pkg string
+		routes    []rte.Route
+		err       string
+	}{
+		{
+			name: "type",
+			pkg:  "routes",
+			routes: []rte.Route{
+				{Name: "Template", Method: "GET", Path: "/template", Handler: h},
+			},
+			err: `route "GET /template": name "Template" is reserved`,
+		},
+		{
+			name: "import",
+			pkg:  "routes",
+			routes: []rte.Route{
+				{Name: "url", Method: "GET", Path: "/url", Handler: h},
+			},
+			err: `route "GET /url": name "url" is reserved`,
+		},
+		{
+			name: "main",
+			pkg:  "main",
+			routes: []rte.Route{
+				{Name: "main", Method: "GET", Path: "/main", Handler: h},
+			},
+			err: `route "GET /main": name "main" is reserved`,
+		},
+		{
+			name: "path function",
+			pkg:  "routes",
+			routes: []rte.Route{
+				{Name: "XPath", Method: "GET", Path: "/x/path", Handler: h},
+				{Name: "X", Method: "GET", Path: "/x", Handler: h},
+			},
+			err: `route "GET /x": function XPath collides with route "GET /x/path"`,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := rte.Must(c.routes).ExportConstants(c.pkg)
+			if err == nil || err.Error() != c.err {
+				t.Fatalf("want error %q but got %v", c.err, err)
+			}
+		})
+	}
+
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain unavailable")
+	}
+
+	tbl := rte.Must([]rte.Route{
+		{Name: "Health", Method: "GET", Path: "/health", Handler: h},
+		{Method: "GET", Path: "/unnamed", Handler: h},
+		{Name: "GetUser", Method: "GET", Path: "/users/:user_id", Handler: h},
+		{Name: "GetPost", Method: "GET", Path: "/users/:user_id/posts/:post_id/", Handler: h},
+		{Name: "Odd", Method: "GET", Path: "/odd/:type/:id/:ID", Handler: h},
+		{Name: "GetArticle", Method: "GET", Path: "/articles/:id.:format", Handler: h},
+		{Name: "Shadow", Method: "GET", Path: "/shadow/:with_format.:format", Handler: h},
+	})
+
+	src, err := tbl.ExportConstants("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(src, "unnamed") {
+		t.Fatalf("unnamed route exported:\n%v", src)
+	}
+
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"go.mod":    "module client\n\ngo 1.19\n",
+		"routes.go": src,
+		"main.go": `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(Health, HealthPath())
+	fmt.Println(GetUser, GetUserPath("a b/c"))
+	fmt.Println(GetPost, GetPostPath("abc", "123"))
+	fmt.Println(Odd, OddPath("x", "y", "z"))
+	fmt.Println(GetArticle, GetArticlePath("1", "json"), GetArticlePath("1", ""))
+	fmt.Println(Shadow, ShadowPath("a", "json"))
+}
+`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goBin, "run", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("generated source failed: %v\n%s\n%v", err, out, src)
+	}
+
+	want := `/health /health
+/users/:user_id /users/a%20b%2Fc
+/users/:user_id/posts/:post_id/ /users/abc/posts/123/
+/odd/:type/:id/:ID /odd/x/y/z
+/articles/:id.:format /articles/1.json /articles/1
+/shadow/:with_format.:format /shadow/a.json
+`
+	if string(out) != want {
+		t.Fatalf("want:\n%v\ngot:\n%s", want, out)
+	}
+}
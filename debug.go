@@ -0,0 +1,67 @@
+package rte
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/jwilner/rte/internal/funcs"
+)
+
+// WithMatchContext stores the Match of each request in its context, where it can be retrieved with MatchFromContext
+// by handlers and middleware. It's off by default, as it costs an allocation per request.
+func WithMatchContext() Option {
+	return func(t *Table) {
+		t.matchContext = true
+	}
+}
+
+// MatchFromContext returns the Match stored by a table built with WithMatchContext and whether or not there was one.
+func MatchFromContext(ctx context.Context) (Match, bool) {
+	m, ok := ctx.Value(ctxKeyMatch).(Match)
+	return m, ok
+}
+
+func withMatch(h funcs.Handler, route Route, idx int, names []string) funcs.Handler {
+	return func(w http.ResponseWriter, r *http.Request, pathVars funcs.PathVars) {
+		m := Match{Route: route, Index: idx, Names: names, Vars: append([]string{}, pathVars[:len(names)]...)}
+		h(w, r.WithContext(context.WithValue(r.Context(), ctxKeyMatch, m)), pathVars)
+	}
+}
+
+// DebugInfo is the body written by DebugHandler
+type DebugInfo struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Template is the path of the matched route, e.g. "/users/:user_id"; it's empty if the Match is unavailable
+	Template string `json:"template"`
+	// Vars are the values of the path variables, in order
+	Vars []string `json:"vars"`
+	// Headers holds the values of the selected headers which were sent
+	Headers map[string][]string `json:"headers"`
+}
+
+// DebugHandler returns a handler which echoes the request's method and path, the matched route's template and path
+// variables, and the values of the provided headers as JSON (see DebugInfo); e.g. for checking how requests are
+// routed during integration testing. The template and variables are only available if the table is built with
+// WithMatchContext. Because it reveals request details, it's never registered implicitly -- it has to be routed
+// explicitly, ideally only in non-production builds:
+//
+//	rte.Must(rte.Routes("GET /debug/:a/:b", rte.DebugHandler("X-Request-Id")), rte.WithMatchContext())
+func DebugHandler(headers ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := DebugInfo{Method: r.Method, Path: r.URL.EscapedPath(), Vars: []string{}, Headers: map[string][]string{}}
+		if m, ok := MatchFromContext(r.Context()); ok {
+			info.Template = m.Route.Path
+			info.Vars = m.Vars
+		}
+		for _, name := range headers {
+			if vals := r.Header.Values(name); len(vals) > 0 {
+				info.Headers[http.CanonicalHeaderKey(name)] = vals
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(info)
+	})
+}
@@ -0,0 +1,92 @@
+package rte_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jwilner/rte"
+)
+
+func TestDebugHandler(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opts []rte.Option
+		want rte.DebugInfo
+	}{
+		{
+			"match context",
+			[]rte.Option{rte.WithMatchContext()},
+			rte.DebugInfo{
+				Method:   "GET",
+				Path:     "/debug/abc/123",
+				Template: "/debug/:a/:b",
+				Vars:     []string{"abc", "123"},
+				Headers:  map[string][]string{"X-Request-Id": {"req-1"}},
+			},
+		},
+		{
+			"no match context",
+			nil,
+			rte.DebugInfo{
+				Method:  "GET",
+				Path:    "/debug/abc/123",
+				Vars:    []string{},
+				Headers: map[string][]string{"X-Request-Id": {"req-1"}},
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tbl := rte.Must(rte.Routes("GET /debug/:a/:b", rte.DebugHandler("x-request-id", "X-Missing")), c.opts...)
+
+			r := httptest.NewRequest("GET", "/debug/abc/123", nil)
+			r.Header.Set("X-Request-Id", "req-1")
+			r.Header.Set("Authorization", "secret")
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, r)
+
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("unexpected content type %q", ct)
+			}
+			var got rte.DebugInfo
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("want %+v but got %+v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestMatchFromContext(t *testing.T) {
+	var (
+		got rte.Match
+		ok  bool
+	)
+	mw := rte.MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		got, ok = rte.MatchFromContext(r.Context())
+		next.ServeHTTP(w, r)
+	})
+	tbl := rte.Must(
+		rte.Wrap(mw, rte.Routes(
+			"GET /foo", func(w http.ResponseWriter, r *http.Request) {},
+			"GET /foo/:id", func(w http.ResponseWriter, r *http.Request, id string) {},
+		)),
+		rte.WithMatchContext(),
+	)
+
+	tbl.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo/123", nil))
+	if !ok {
+		t.Fatal("expected a match in the context")
+	}
+	if got.Index != 1 || got.Route.Path != "/foo/:id" || !reflect.DeepEqual(got.Vars, []string{"123"}) {
+		t.Fatalf("unexpected match %+v", got)
+	}
+
+	if _, ok := rte.MatchFromContext(httptest.NewRequest("GET", "/", nil).Context()); ok {
+		t.Fatal("expected no match in an unrouted context")
+	}
+}
@@ -0,0 +1,35 @@
+package rte
+
+// MatchVisits returns the number of nodes the matcher visits when matching the method and path, and the number of
+// times it backtracks to try a branch node's other child
+func (t *Table) MatchVisits(method, path string) (visits, backtracks int) {
+	var (
+		variables PathVars
+		c         visitCounter
+	)
+	_, mask := t.acceptMethods(method)
+	t.matchPath(mask, t.cleanPath(path), variables[:], &c)
+	return c.visits, c.backtracks
+}
+
+// visitCounter is a matchTracer counting the nodes visited and the backtracks made
+type visitCounter struct {
+	visits, backtracks int
+}
+
+func (c *visitCounter) branch(*node, int, *node, *node) {}
+
+func (c *visitCounter) backtrack(*node, *node, *node, bool) {
+	c.backtracks++
+}
+
+func (c *visitCounter) visit(n *node) {
+	// WorstCaseDepth doesn't count the roots, whose labels are empty
+	if n.label != "" {
+		c.visits++
+	}
+}
+
+func (c *visitCounter) reject(*node, int, int, int) {}
+
+func (c *visitCounter) fold() {}
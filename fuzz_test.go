@@ -0,0 +1,27 @@
+package rte_test
+
+import (
+	"testing"
+
+	"github.com/jwilner/rte"
+)
+
+func FuzzMatchWork(f *testing.F) {
+	tbl := rte.Must(pathologicalRoutes())
+	depth := tbl.WorstCaseDepth()
+	for _, seed := range []string{"/", "/x/x/x/x/y", "/x/x/x/x/z", "/a/b/c/d", "/x//x//", "/xx/x/x/x/z/"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, path string) {
+		visits, backtracks := tbl.MatchVisits("GET", path)
+		// every node consumes at least a byte of the path, except a lone wildcard, which is followed by a node
+		// consuming the separator -- so each descent, whether from the root or after a backtrack, visits at most
+		// 2*len(path)+1 nodes
+		if bound := (backtracks + 1) * (2*len(path) + 1); visits > bound {
+			t.Fatalf("%q visited %v nodes with %v backtracks; want at most %v", path, visits, backtracks, bound)
+		}
+		if visits > depth {
+			t.Fatalf("%q visited %v nodes but the worst case depth is %v", path, visits, depth)
+		}
+	})
+}
@@ -1,3 +1,3 @@
 module github.com/jwilner/rte
 
-go 1.15
+go 1.19
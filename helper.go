@@ -1,11 +1,17 @@
 package rte
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
-
-	"github.com/jwilner/rte/internal/funcs"
+	"sync/atomic"
+	"time"
 )
 
 // Routes is a vanity constructor for constructing literal routing tables. It enforces types at runtime. An invocation
@@ -23,6 +29,10 @@ import (
 // - []Route
 // - "PATH", []Route (identical to rte.Prefix("PATH", routes))
 // - "PATH", []Route, middleware (identical to rte.Wrap(rte.Prefix("PATH", routes), middleware))
+//
+// Middleware provided alongside a handler is the route's own; middleware provided alongside []Route is applied with
+// Wrap, so it's invoked before (and exits after) any middleware the routes already have. See Wrap for the resulting
+// order.
 func Routes(is ...interface{}) []Route {
 	var routes []Route
 
@@ -79,7 +89,7 @@ func Routes(is ...interface{}) []Route {
 					r.Method = split[0]
 				}
 			}
-			if _, _, ok := funcs.Convert(v); !ok {
+			if _, _, ok := convertBuiltin(v, nil, nil, nil); !ok {
 				panic(fmt.Sprintf(
 					"rte.Routes: invalid handler for \"%v %v\" in position %v: %T",
 					r.Method,
@@ -205,7 +215,15 @@ func DefaultMethod(hndlr interface{}, routes []Route) []Route {
 }
 
 // Wrap registers a middleware across all provide routes. If a middleware is already set, that middleware will be
-// invoked second.
+// invoked second. Consequently, the last applied Wrap is always outermost and a route's own middleware is always
+// innermost; e.g. for
+//
+//	rte.Wrap(global, rte.Routes(
+//		"/group", rte.Routes("GET /foo", handler, perRoute), group,
+//	))
+//
+// a request to GET /group/foo enters global, group, and perRoute in that order before reaching handler, and then
+// exits perRoute, group, and global in that order.
 func Wrap(mw Middleware, routes []Route) []Route {
 	var copied []Route
 	for _, r := range routes {
@@ -219,20 +237,83 @@ func Wrap(mw Middleware, routes []Route) []Route {
 	return copied
 }
 
+// WrapIf registers a middleware across those of the provided routes for which the predicate returns true, just as Wrap
+// does; the other routes are returned untouched. Route order is preserved.
+func WrapIf(pred func(Route) bool, mw Middleware, routes []Route) []Route {
+	var copied []Route
+	for _, r := range routes {
+		if pred(r) {
+			r = Wrap(mw, []Route{r})[0]
+		}
+		copied = append(copied, r)
+	}
+	return copied
+}
+
+// wrapEach registers a middleware built for each of the provided routes, e.g. capturing its pattern, just as Wrap does
+func wrapEach(build func(Route) Middleware, routes []Route) []Route {
+	var copied []Route
+	for _, r := range routes {
+		copied = append(copied, Wrap(build(r), []Route{r})...)
+	}
+	return copied
+}
+
+// MaxBodyBytes limits the size of the request bodies accepted by the provided routes to n bytes, just as Wrap would
+// with a middleware applying http.MaxBytesReader: reads beyond the limit fail, and the server closes the connection
+// after the response. Routes with NoBodyLimit set are returned untouched, so the limit can be applied across a table
+// while exempting e.g. streaming uploads; a limit registered otherwise, e.g. by Wrap, applies regardless.
+func MaxBodyBytes(n int64, routes []Route) []Route {
+	return WrapIf(func(r Route) bool {
+		return !r.NoBodyLimit
+	}, MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		r.Body = http.MaxBytesReader(w, r.Body, n)
+		next.ServeHTTP(w, r)
+	}), routes)
+}
+
 // Compose combines one or more middlewares into a single middleware. The composed middleware will proceed left to right
-// through the middleware (and exit right to left).
+// through the middleware (and exit right to left). Composed middlewares are flattened, so composing them again, e.g.
+// via repeated calls to Wrap, doesn't add further layers of indirection.
 func Compose(mw Middleware, mws ...Middleware) Middleware {
-	mws = append([]Middleware{mw}, mws...)
-	mw = mws[len(mws)-1]
-	for i := len(mws) - 2; i >= 0; i-- {
-		mw1, mw2 := mws[i], mw
-		mw = MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
-			mw1.Handle(w, r, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				mw2.Handle(w, r, next)
-			}))
+	var c chain
+	for _, m := range append([]Middleware{mw}, mws...) {
+		if nested, ok := m.(chain); ok {
+			c = append(c, nested...)
+		} else {
+			c = append(c, m)
+		}
+	}
+	if len(c) == 1 {
+		return c[0]
+	}
+	return c
+}
+
+// chain is a flattened composition of middlewares; see Compose
+type chain []Middleware
+
+// Handle dispatches through each of the middlewares in turn, and then to next
+func (c chain) Handle(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	for i := len(c) - 1; i > 0; i-- {
+		mw, n := c[i], next
+		next = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mw.Handle(w, r, n)
 		})
 	}
-	return mw
+	c[0].Handle(w, r, next)
+}
+
+// middlewareDepth returns the number of layers in a middleware, counting each middleware composed by Compose
+func middlewareDepth(mw Middleware) int {
+	switch mw := mw.(type) {
+	case nil:
+		return 0
+	case chain:
+		return len(mw)
+	default:
+		return 1
+	}
 }
 
 // RecoveryMiddleware returns a middleware which converts any panics into 500 status http errors and stops the panic. If
@@ -258,3 +339,360 @@ func RecoveryMiddleware(log interface{ Println(...interface{}) }) Middleware {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// DrainMiddleware returns a middleware which, while draining is set, responds to new requests with a 503 and a
+// Retry-After header of retryAfter (rounded up to whole seconds; omitted if it's not positive), e.g. while shutting
+// down gracefully. Requests already being handled are unaffected, so they can complete.
+func DrainMiddleware(draining *atomic.Bool, retryAfter time.Duration) Middleware {
+	secs := strconv.Itoa(int((retryAfter + time.Second - 1) / time.Second))
+	return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		if draining.Load() {
+			if retryAfter > 0 {
+				w.Header().Set("Retry-After", secs)
+			}
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HeaderLimitMiddleware returns a middleware which responds with a 431 (Request Header Fields Too Large), without
+// calling the handler, to requests with more than maxHeaders header fields or whose header fields total more than
+// maxHeaderBytes bytes. Each value of a repeated header counts as a field, and a field's size is that of its name
+// and value. A non-positive limit isn't enforced. The server's own http.Server.MaxHeaderBytes still applies first.
+func HeaderLimitMiddleware(maxHeaders, maxHeaderBytes int) Middleware {
+	return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		var count, size int
+		for name, values := range r.Header {
+			count += len(values)
+			for _, v := range values {
+				size += len(name) + len(v)
+			}
+		}
+		if maxHeaders > 0 && count > maxHeaders || maxHeaderBytes > 0 && size > maxHeaderBytes {
+			http.Error(
+				w,
+				http.StatusText(http.StatusRequestHeaderFieldsTooLarge),
+				http.StatusRequestHeaderFieldsTooLarge,
+			)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LastModifiedMiddleware returns a middleware which sets the Last-Modified header of GET and HEAD responses to the time
+// returned by modTime -- e.g. of the underlying content -- and responds with a 304, without calling the handler, when
+// the request's If-Modified-Since is at or after it. Times are compared at the header's one second precision. A zero
+// time omits the header, and a malformed If-Modified-Since, or one accompanied by an If-None-Match (which takes
+// precedence), is ignored.
+func LastModifiedMiddleware(modTime func(r *http.Request) time.Time) Middleware {
+	return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		mod := modTime(r)
+		if mod.IsZero() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mod = mod.Truncate(time.Second)
+		w.Header().Set("Last-Modified", mod.UTC().Format(http.TimeFormat))
+		if ims := r.Header.Get("If-Modified-Since"); ims != "" && r.Header.Get("If-None-Match") == "" {
+			if since, err := http.ParseTime(ims); err == nil && !mod.After(since) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ParsedQueryMiddleware returns a middleware which parses the request's query string once, storing the values in the
+// request's context for Query, so that middleware and handlers needn't each call r.URL.Query(), which parses it anew.
+// If the values are already stored, e.g. by an outer ParsedQueryMiddleware, they're reused.
+func ParsedQueryMiddleware() Middleware {
+	return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		if _, ok := r.Context().Value(ctxKeyQuery).(url.Values); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyQuery, r.URL.Query())))
+	})
+}
+
+// Query returns the query values stored by ParsedQueryMiddleware, or nil if there are none. The values are shared by
+// everything handling the request, so they shouldn't be modified.
+func Query(ctx context.Context) url.Values {
+	q, _ := ctx.Value(ctxKeyQuery).(url.Values)
+	return q
+}
+
+// Pool runs functions on a bounded set of workers; see PoolMiddleware.
+type Pool interface {
+	// Submit schedules f to be run, returning false if the pool is saturated and it won't be
+	Submit(f func()) bool
+}
+
+// PoolMiddleware returns a middleware which runs the rest of the handler chain on the pool, e.g. to prevent CPU-heavy
+// endpoints from starving the rest of the server; if the pool is saturated, the response is a 503. The request's
+// goroutine waits for the handler to complete, so the ResponseWriter is never used after ServeHTTP returns, and any
+// panic is re-raised on it (e.g. for RecoveryMiddleware).
+func PoolMiddleware(pool Pool) Middleware {
+	return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		var (
+			done     = make(chan struct{})
+			panicked interface{}
+		)
+		if !pool.Submit(func() {
+			defer close(done)
+			defer func() {
+				panicked = recover()
+			}()
+			next.ServeHTTP(w, r)
+		}) {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		<-done
+		if panicked != nil {
+			panic(panicked)
+		}
+	})
+}
+
+// Limiter decides whether requests are permitted; see RateLimitByVar.
+type Limiter interface {
+	// Allow reports whether a request with the key is permitted now, consuming from the key's allowance if so
+	Allow(key string) bool
+}
+
+// RateLimitByVar returns a middleware limiting requests by the value of the named path variable, e.g. "tenant" for
+// "/tenants/:tenant/reports", so that each value has its own allowance; requests the limiter rejects receive a 429.
+// The variable is read from the request's Match, so the table must be built with WithMatchContext; if the variable
+// is unavailable, the route is misconfigured and the response is a 500.
+func RateLimitByVar(varName string, limiter Limiter) Middleware {
+	return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		m, _ := MatchFromContext(r.Context())
+		key, ok := m.Var(varName)
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if !limiter.Allow(key) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IPAllowlist is a middleware which responds with a 403 to requests from clients whose IP addresses aren't within
+// any of its prefixes; see IPAllowlistMiddleware.
+type IPAllowlist struct {
+	// Prefixes are the allowed ranges
+	Prefixes []netip.Prefix
+	// TrustForwardedFor determines the client's address from the last entry of the X-Forwarded-For header, if it's
+	// present, rather than the connection's remote address. Only set it if the server is reachable solely through a
+	// proxy which appends to the header, as otherwise clients can claim whichever address they like; behind more than
+	// one proxy, the last entry is the address of the previous proxy, not the client.
+	TrustForwardedFor bool
+}
+
+// IPAllowlistMiddleware returns a middleware allowing requests only from clients within the provided ranges, in CIDR
+// notation (e.g. "10.0.0.0/8") or as single addresses; it panics if any can't be parsed. By default, the client's
+// address is the connection's (see IPAllowlist.TrustForwardedFor).
+//
+//	admin := rte.Wrap(rte.IPAllowlistMiddleware("10.0.0.0/8", "::1"), adminRoutes)
+func IPAllowlistMiddleware(cidrs ...string) *IPAllowlist {
+	a := new(IPAllowlist)
+	for _, c := range cidrs {
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(c)
+			if addrErr != nil {
+				panic(fmt.Sprintf("rte.IPAllowlistMiddleware: invalid range %q: %v", c, err))
+			}
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		a.Prefixes = append(a.Prefixes, p.Masked())
+	}
+	return a
+}
+
+// Handle implements Middleware
+func (a *IPAllowlist) Handle(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if addr, ok := a.clientAddr(r); ok {
+		for _, p := range a.Prefixes {
+			if p.Contains(addr) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+	}
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+}
+
+func (a *IPAllowlist) clientAddr(r *http.Request) (netip.Addr, bool) {
+	raw := r.RemoteAddr
+	if fwd := r.Header.Values("X-Forwarded-For"); a.TrustForwardedFor && len(fwd) > 0 {
+		last := fwd[len(fwd)-1]
+		raw = strings.TrimSpace(last[strings.LastIndexByte(last, ',')+1:])
+	}
+
+	if ap, err := netip.ParseAddrPort(raw); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(raw)
+	return addr.Unmap(), err == nil
+}
+
+// TrimTrailingDots removes trailing dots from each segment of the provided path, as delimited by sep -- e.g.
+// "/foo./bar.." becomes "/foo/bar" for a sep of '/'. Segments consisting only of dots (i.e. "." and "..") are left
+// untouched. Passing the Table's separator, it's suitable for use as (or within) the Table's NormalizeUnicode function.
+func TrimTrailingDots(path string, sep byte) string {
+	delim := string(sep)
+	if !strings.Contains(path, "."+delim) && !strings.HasSuffix(path, ".") {
+		return path
+	}
+
+	segments := strings.Split(path, delim)
+	for i, s := range segments {
+		if trimmed := strings.TrimRight(s, "."); trimmed != "" {
+			segments[i] = trimmed
+		}
+	}
+	return strings.Join(segments, delim)
+}
+
+// FirstOf combines handlers into a single handler which invokes each in turn until one of them writes a response (i.e.
+// calls Write, or WriteHeader with a non-informational status); the remaining handlers are skipped. If none writes a
+// response, nothing is written. Note that headers set by a handler which doesn't go on to write a response are still
+// visible to later handlers.
+func FirstOf(handlers ...http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &trackingWriter{ResponseWriter: w}
+		for _, h := range handlers {
+			h.ServeHTTP(tw, r)
+			if tw.written {
+				return
+			}
+		}
+	})
+}
+
+// Redirects converts a map of source paths to targets into GET and HEAD routes which redirect to the target with the
+// given status code (e.g. http.StatusMovedPermanently). Routes are ordered by source path. A source which collides with
+// another route in the table is reported by New as a duplicate, just as any other route would be.
+func Redirects(redirects map[string]string, code int) []Route {
+	sources := make([]string, 0, len(redirects))
+	for src := range redirects {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+
+	var routes []Route
+	for _, src := range sources {
+		target := redirects[src]
+		h := func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, target, code)
+		}
+		routes = append(routes,
+			Route{Method: http.MethodGet, Path: src, Handler: h},
+			Route{Method: http.MethodHead, Path: src, Handler: h},
+		)
+	}
+	return routes
+}
+
+// SPAFallback returns a handler, suitable for use as the table's Default, which serves the file at indexPath in
+// response to any GET or HEAD request accepting text/html -- i.e. browser navigations within a single page app, e.g.
+// to "/app/dashboard/settings" -- and otherwise defers to the table's current Default, so that misses for assets and
+// API calls are still 404s. The attempted path remains available to the app as the page's location.
+//
+//	tbl.Default = tbl.SPAFallback("dist/index.html")
+func (t *Table) SPAFallback(indexPath string) http.Handler {
+	next := t.Default
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+			!strings.Contains(r.Header.Get("Accept"), "text/html") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		f, err := os.Open(indexPath)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+
+		fi, err := f.Stat()
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		http.ServeContent(w, r, fi.Name(), fi.ModTime(), f)
+	})
+}
+
+// CatchAllExcept returns an option making h the table's fallback for every request no route matches, except those
+// whose (decoded) paths begin with one of the excluded prefixes, which are still handled by the previous Default --
+// i.e. 404s, unless Default was replaced by an earlier option. It makes explicit that e.g. a single page app serves
+// every path but those of its API and assets:
+//
+//	rte.Must(routes, rte.CatchAllExcept(index, "/api/", "/static/"))
+func CatchAllExcept(h http.Handler, excludedPrefixes ...string) Option {
+	return func(t *Table) {
+		next := t.Default
+		t.Default = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, p := range excludedPrefixes {
+				if strings.HasPrefix(r.URL.Path, p) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// EnumHandler is the form of handler returned by Enum. The table invokes it with the route's path variable and the
+// handler for requests no route matches, to which it defers if the variable isn't allowed.
+type EnumHandler func(w http.ResponseWriter, r *http.Request, v string, miss http.Handler)
+
+// Enum adapts a handler taking a single path variable of a string type, e.g. "GET /orders/:status", so that it's only
+// invoked if the variable is exactly (i.e. case sensitively) one of the allowed values; otherwise, the request is
+// handled as if no route matched it -- by the table's Default or DefaultByPrefix -- as the path doesn't identify
+// anything.
+//
+//	func handleStatus(w http.ResponseWriter, r *http.Request, s Status) { /* ... */ }
+//
+//	rte.Routes("GET /orders/:status", rte.Enum([]Status{Pending, Shipped}, handleStatus))
+func Enum[T ~string](allowed []T, h func(w http.ResponseWriter, r *http.Request, v T)) EnumHandler {
+	values := make(map[string]T, len(allowed))
+	for _, v := range allowed {
+		values[string(v)] = v
+	}
+	return func(w http.ResponseWriter, r *http.Request, v string, miss http.Handler) {
+		t, ok := values[v]
+		if !ok {
+			miss.ServeHTTP(w, r)
+			return
+		}
+		h(w, r, t)
+	}
+}
+
+// AllMethods returns a route handling every method at the path; it's equivalent to using MethodAny as the method:
+//
+//	rte.Routes(rte.AllMethods("/debug/echo", echo))
+func AllMethods(path string, handler interface{}) Route {
+	return Route{Method: MethodAny, Path: path, Handler: handler}
+}
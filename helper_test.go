@@ -2,12 +2,21 @@ package rte_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/jwilner/rte"
 )
@@ -173,6 +182,89 @@ func TestGlobalMiddleware(t *testing.T) {
 	})
 }
 
+// orderMW records entering and exiting
+type orderMW string
+
+func (s orderMW) Handle(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	_, _ = fmt.Fprintf(w, "enter %s\n", s)
+	next.ServeHTTP(w, r)
+	_, _ = fmt.Fprintf(w, "exit %s\n", s)
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "handler\n")
+	}
+	want := "enter global\nenter group\nenter route\nhandler\nexit route\nexit group\nexit global\n"
+
+	for _, c := range []struct {
+		name   string
+		routes []rte.Route
+	}{
+		{
+			"routes",
+			rte.Wrap(orderMW("global"), rte.Routes(
+				"/group", rte.Routes("GET /foo", handler, orderMW("route")), orderMW("group"),
+			)),
+		},
+		{
+			"wraps",
+			rte.Wrap(orderMW("global"), rte.Wrap(orderMW("group"), []rte.Route{
+				{Method: "GET", Path: "/group/foo", Handler: handler, Middleware: orderMW("route")},
+			})),
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			rte.Must(c.routes).ServeHTTP(w, httptest.NewRequest("GET", "/group/foo", nil))
+			if res := w.Body.String(); res != want {
+				t.Errorf("Wanted %q but got %q", want, res)
+			}
+		})
+	}
+}
+
+func TestWrapIf(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	isAdmin := func(r rte.Route) bool {
+		return strings.HasPrefix(r.Path, "/admin")
+	}
+
+	t.Run("setsMW", func(t *testing.T) {
+		mw := mockMW(true)
+		rts := rte.WrapIf(isAdmin, mw, []rte.Route{
+			{Method: "GET", Path: "/admin/users"},
+			{Method: "GET", Path: "/users"},
+			{Method: "POST", Path: "/admin"},
+		})
+		want := []rte.Route{
+			{Method: "GET", Path: "/admin/users", Middleware: mw},
+			{Method: "GET", Path: "/users"},
+			{Method: "POST", Path: "/admin", Middleware: mw},
+		}
+		if !reflect.DeepEqual(rts, want) {
+			t.Errorf("Wanted %v but got %v", want, rts)
+		}
+	})
+	t.Run("composes", func(t *testing.T) {
+		tbl := rte.Must(rte.WrapIf(isAdmin, stringMW("admin"), rte.Routes(
+			"GET /admin/users", h, stringMW("users"),
+			"GET /users", h, stringMW("users"),
+		)))
+
+		for path, want := range map[string]string{
+			"/admin/users": "admin\nusers\n",
+			"/users":       "users\n",
+		} {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if res := w.Body.String(); res != want {
+				t.Errorf("%v: Wanted %q but got %q", path, want, res)
+			}
+		}
+	})
+}
+
 func TestRoutes(t *testing.T) {
 
 	panics := func(t *testing.T, f func(), want interface{}) {
@@ -386,6 +478,425 @@ func TestCompose(t *testing.T) {
 			t.Fatalf("Wanted \"1\n2\n3\n\" but got %v", r)
 		}
 	})
+	t.Run("nested", func(t *testing.T) {
+		mw := rte.Compose(rte.Compose(stringMW("1"), stringMW("2")), rte.Compose(stringMW("3"), stringMW("4")))
+		if r := getBody(mw); r != "1\n2\n3\n4\n" {
+			t.Fatalf("Wanted \"1\n2\n3\n4\n\" but got %v", r)
+		}
+	})
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	count := func(w http.ResponseWriter, r *http.Request) {
+		n, err := io.Copy(io.Discard, r.Body)
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		_, _ = fmt.Fprint(w, n)
+	}
+
+	tbl := rte.Must(rte.MaxBodyBytes(10, []rte.Route{
+		{Method: "POST", Path: "/small", Handler: count},
+		{Method: "POST", Path: "/upload", Handler: count, NoBodyLimit: true},
+	}))
+
+	for _, c := range []struct {
+		path     string
+		size     int
+		wantCode int
+		wantBody string
+	}{
+		{"/small", 10, 200, "10"},
+		{"/small", 11, 413, "http: request body too large\n"},
+		{"/upload", 1 << 20, 200, "1048576"},
+	} {
+		t.Run(fmt.Sprint(c.path, c.size), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("POST", c.path, strings.NewReader(strings.Repeat("a", c.size))))
+			if w.Code != c.wantCode || w.Body.String() != c.wantBody {
+				t.Fatalf("want %v %q but got %v %q", c.wantCode, c.wantBody, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDrainMiddleware(t *testing.T) {
+	var draining atomic.Bool
+	tbl := rte.Must(rte.Wrap(rte.DrainMiddleware(&draining, 1500*time.Millisecond), rte.Routes(
+		"GET /foo", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "foo")
+		},
+	)))
+
+	for _, c := range []struct {
+		name       string
+		draining   bool
+		wantCode   int
+		wantRetry  string
+		wantPrefix string
+	}{
+		{"serving", false, 200, "", "foo"},
+		{"draining", true, 503, "2", "Service Unavailable"},
+		{"resumed", false, 200, "", "foo"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			draining.Store(c.draining)
+
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", "/foo", nil))
+			if w.Code != c.wantCode {
+				t.Fatalf("want %v but got %v", c.wantCode, w.Code)
+			}
+			if retry := w.Header().Get("Retry-After"); retry != c.wantRetry {
+				t.Fatalf("want Retry-After %q but got %q", c.wantRetry, retry)
+			}
+			if body := w.Body.String(); !strings.HasPrefix(body, c.wantPrefix) {
+				t.Fatalf("want body starting %q but got %q", c.wantPrefix, body)
+			}
+		})
+	}
+
+	t.Run("in flight", func(t *testing.T) {
+		draining.Store(false)
+		started, finish := make(chan struct{}), make(chan struct{})
+		tbl := rte.Must(rte.Wrap(rte.DrainMiddleware(&draining, 0), rte.Routes(
+			"GET /slow", func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-finish
+			},
+		)))
+
+		done := make(chan int)
+		go func() {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+			done <- w.Code
+		}()
+
+		<-started
+		draining.Store(true)
+		w := httptest.NewRecorder()
+		tbl.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+		if w.Code != 503 || w.Header().Get("Retry-After") != "" {
+			t.Fatalf("want 503 without Retry-After but got %v %q", w.Code, w.Header().Get("Retry-After"))
+		}
+
+		close(finish)
+		if code := <-done; code != 200 {
+			t.Fatalf("want in flight request to complete with 200 but got %v", code)
+		}
+	})
+}
+
+func TestHeaderLimitMiddleware(t *testing.T) {
+	tbl := rte.Must(rte.Wrap(rte.HeaderLimitMiddleware(3, 30), rte.Routes(
+		"GET /", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "ok")
+		},
+	)))
+
+	for _, c := range []struct {
+		name    string
+		headers [][2]string
+		want    int
+	}{
+		{"compliant", [][2]string{{"A", "1"}, {"B", "2"}, {"C", "3"}}, 200},
+		{"too many", [][2]string{{"A", "1"}, {"B", "2"}, {"C", "3"}, {"D", "4"}}, 431},
+		{"repeated", [][2]string{{"A", "1"}, {"A", "2"}, {"A", "3"}, {"A", "4"}}, 431},
+		{"at byte limit", [][2]string{{"Authorization", strings.Repeat("x", 17)}}, 200},
+		{"too large", [][2]string{{"Authorization", strings.Repeat("x", 18)}}, 431},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			for _, h := range c.headers {
+				r.Header.Add(h[0], h[1])
+			}
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, r)
+			if w.Code != c.want {
+				t.Fatalf("want %v but got %v %q", c.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestLastModifiedMiddleware(t *testing.T) {
+	mod := time.Date(2024, 3, 1, 12, 30, 15, 500, time.UTC)
+	tbl := rte.Must(rte.Wrap(rte.LastModifiedMiddleware(func(r *http.Request) time.Time {
+		if r.URL.Path == "/unknown" {
+			return time.Time{}
+		}
+		return mod
+	}), rte.Routes(
+		"GET /content", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "content")
+		},
+		"POST /content", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "posted")
+		},
+		"GET /unknown", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "unknown")
+		},
+	)))
+
+	for _, c := range []struct {
+		name, method, path   string
+		headers              map[string]string
+		wantCode             int
+		wantBody, wantHeader string
+	}{
+		{"no condition", "GET", "/content", nil, 200, "content", "Fri, 01 Mar 2024 12:30:15 GMT"},
+		{
+			"equal",
+			"GET", "/content", map[string]string{"If-Modified-Since": "Fri, 01 Mar 2024 12:30:15 GMT"},
+			304, "", "Fri, 01 Mar 2024 12:30:15 GMT",
+		},
+		{
+			"newer",
+			"GET", "/content", map[string]string{"If-Modified-Since": "Sat, 02 Mar 2024 00:00:00 GMT"},
+			304, "", "Fri, 01 Mar 2024 12:30:15 GMT",
+		},
+		{
+			"older",
+			"GET", "/content", map[string]string{"If-Modified-Since": "Fri, 01 Mar 2024 12:30:14 GMT"},
+			200, "content", "Fri, 01 Mar 2024 12:30:15 GMT",
+		},
+		{
+			"malformed",
+			"GET", "/content", map[string]string{"If-Modified-Since": "yesterday"},
+			200, "content", "Fri, 01 Mar 2024 12:30:15 GMT",
+		},
+		{
+			"if-none-match takes precedence",
+			"GET", "/content",
+			map[string]string{"If-Modified-Since": "Sat, 02 Mar 2024 00:00:00 GMT", "If-None-Match": `"abc"`},
+			200, "content", "Fri, 01 Mar 2024 12:30:15 GMT",
+		},
+		{
+			"other methods",
+			"POST", "/content", map[string]string{"If-Modified-Since": "Sat, 02 Mar 2024 00:00:00 GMT"},
+			200, "posted", "",
+		},
+		{
+			"unknown time",
+			"GET", "/unknown", map[string]string{"If-Modified-Since": "Sat, 02 Mar 2024 00:00:00 GMT"},
+			200, "unknown", "",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.method, c.path, nil)
+			for k, v := range c.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, r)
+			if w.Code != c.wantCode || w.Body.String() != c.wantBody {
+				t.Fatalf("want %v %q but got %v %q", c.wantCode, c.wantBody, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Last-Modified"); got != c.wantHeader {
+				t.Fatalf("want Last-Modified %q but got %q", c.wantHeader, got)
+			}
+		})
+	}
+}
+
+func TestParsedQueryMiddleware(t *testing.T) {
+	var got url.Values
+	tbl := rte.Must(rte.Wrap(rte.Compose(
+		rte.ParsedQueryMiddleware(),
+		rte.MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			// neither reparsed when the query changes nor replaced by a nested ParsedQueryMiddleware
+			r.URL.RawQuery = "changed=1"
+			rte.Query(r.Context()).Set("seen", "middleware")
+			next.ServeHTTP(w, r)
+		}),
+		rte.ParsedQueryMiddleware(),
+	), rte.Routes(
+		"GET /search", func(w http.ResponseWriter, r *http.Request) {
+			got = rte.Query(r.Context())
+		},
+	)))
+
+	tbl.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/search?q=a+b&tag=x&tag=y", nil))
+
+	want := url.Values{"q": {"a b"}, "tag": {"x", "y"}, "seen": {"middleware"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v but got %v", want, got)
+	}
+
+	if q := rte.Query(context.Background()); q != nil {
+		t.Fatalf("want nil without the middleware but got %v", q)
+	}
+}
+
+// semPool runs each function on its own goroutine, but no more than cap(semPool) at once
+type semPool chan struct{}
+
+func (p semPool) Submit(f func()) bool {
+	select {
+	case p <- struct{}{}:
+		go func() {
+			defer func() { <-p }()
+			f()
+		}()
+		return true
+	default:
+		return false
+	}
+}
+
+func TestPoolMiddleware(t *testing.T) {
+	started, finish := make(chan struct{}), make(chan struct{})
+	tbl := rte.Must(rte.Wrap(rte.RecoveryMiddleware(nil), rte.Wrap(rte.PoolMiddleware(make(semPool, 1)), rte.Routes(
+		"GET /slow", func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-finish
+			w.WriteHeader(http.StatusCreated)
+		},
+		"GET /fast", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "fast")
+		},
+		"GET /panic", func(w http.ResponseWriter, r *http.Request) {
+			panic("whoa")
+		},
+	))))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		tbl.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	if w := serve("/fast"); w.Code != 200 || w.Body.String() != "fast" {
+		t.Fatalf("want 200 \"fast\" but got %v %q", w.Code, w.Body.String())
+	}
+
+	done := make(chan int)
+	go func() {
+		done <- serve("/slow").Code
+	}()
+	<-started
+
+	if w := serve("/fast"); w.Code != 503 {
+		t.Fatalf("want 503 while saturated but got %v", w.Code)
+	}
+
+	close(finish)
+	if code := <-done; code != 201 {
+		t.Fatalf("want 201 from the pooled handler but got %v", code)
+	}
+
+	// the worker may not have been released quite yet
+	for i := 0; ; i++ {
+		w := serve("/fast")
+		if w.Code == 200 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("want 200 after the pool drained but got %v", w.Code)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if w := serve("/panic"); w.Code != 500 {
+		t.Fatalf("want panic to be recovered on the request's goroutine with a 500 but got %v", w.Code)
+	}
+}
+
+// countLimiter permits a fixed number of requests per key
+type countLimiter struct {
+	limit int
+	seen  map[string]int
+}
+
+func (l *countLimiter) Allow(key string) bool {
+	l.seen[key]++
+	return l.seen[key] <= l.limit
+}
+
+func TestRateLimitByVar(t *testing.T) {
+	limiter := &countLimiter{limit: 2, seen: make(map[string]int)}
+	h := func(w http.ResponseWriter, r *http.Request, tenant string) {
+		_, _ = fmt.Fprint(w, tenant)
+	}
+	tbl := rte.Must(
+		rte.Wrap(rte.RateLimitByVar("tenant", limiter), rte.Routes(
+			"GET /tenants/:tenant/reports", h,
+			"GET /users/:user", h,
+		)),
+		rte.WithMatchContext(),
+	)
+
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		tbl.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+
+	var got []int
+	for _, tenant := range []string{"a", "a", "b", "a", "b", "b", "c"} {
+		got = append(got, serve("/tenants/"+tenant+"/reports"))
+	}
+	if want := []int{200, 200, 200, 429, 200, 429, 200}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v but got %v", want, got)
+	}
+
+	if code := serve("/users/a"); code != 500 {
+		t.Fatalf("want 500 for a route without the variable but got %v", code)
+	}
+}
+
+func TestIPAllowlistMiddleware(t *testing.T) {
+	allowlist := rte.IPAllowlistMiddleware("10.0.0.0/8", "192.168.1.7", "fd00::/8")
+	tbl := rte.Must(rte.Wrap(allowlist, rte.Routes(
+		"GET /admin", func(w http.ResponseWriter, r *http.Request) {},
+	)))
+
+	for _, c := range []struct {
+		name, remoteAddr string
+		forwardedFor     []string
+		trust            bool
+		want             int
+	}{
+		{"allowed", "10.1.2.3:1234", nil, false, 200},
+		{"allowed single", "192.168.1.7:1234", nil, false, 200},
+		{"allowed v6", "[fd00::1]:1234", nil, false, 200},
+		{"allowed v4 in v6", "[::ffff:10.1.2.3]:1234", nil, false, 200},
+		{"denied", "192.168.1.8:1234", nil, false, 403},
+		{"denied v6", "[2001:db8::1]:1234", nil, false, 403},
+		{"unparseable", "pipe", nil, false, 403},
+		{"forwarded untrusted", "192.168.1.8:1234", []string{"10.1.2.3"}, false, 403},
+		{"forwarded trusted", "192.168.1.8:1234", []string{"10.1.2.3"}, true, 200},
+		{"forwarded trusted uses last", "192.168.1.8:1234", []string{"10.1.2.3, 8.8.8.8"}, true, 403},
+		{"forwarded trusted uses last header", "192.168.1.8:1234", []string{"8.8.8.8", "1.1.1.1,10.1.2.3"}, true, 200},
+		{"forwarded trusted absent", "10.1.2.3:1234", nil, true, 200},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			allowlist.TrustForwardedFor = c.trust
+
+			r := httptest.NewRequest("GET", "/admin", nil)
+			r.RemoteAddr = c.remoteAddr
+			for _, v := range c.forwardedFor {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, r)
+			if w.Code != c.want {
+				t.Fatalf("want %v but got %v", c.want, w.Code)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected a panic")
+			}
+		}()
+		rte.IPAllowlistMiddleware("10.0.0.0/33")
+	})
 }
 
 func TestRecoveryMiddleware(t *testing.T) {
@@ -432,3 +943,293 @@ func TestRecoveryMiddleware(t *testing.T) {
 		}
 	})
 }
+
+func TestTrimTrailingDots(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		sep  byte
+		want string
+	}{
+		{"/", '/', "/"},
+		{"/foo/bar", '/', "/foo/bar"},
+		{"/foo./bar", '/', "/foo/bar"},
+		{"/foo/bar...", '/', "/foo/bar"},
+		{"/foo./bar./", '/', "/foo/bar/"},
+		{"/foo/../bar/.", '/', "/foo/../bar/."},
+		{"/f.o.o./bar", '/', "/f.o.o/bar"},
+		{":foo.:bar..", ':', ":foo:bar"},
+		{":foo:..:.", ':', ":foo:..:."},
+		{"/foo./bar", ':', "/foo./bar"},
+	} {
+		t.Run(fmt.Sprintf("%c%s", c.sep, c.in), func(t *testing.T) {
+			if got := rte.TrimTrailingDots(c.in, c.sep); got != c.want {
+				t.Fatalf("TrimTrailingDots(%q, %q) = %q, want %q", c.in, c.sep, got, c.want)
+			}
+		})
+	}
+}
+
+func TestFirstOf(t *testing.T) {
+	var called []string
+	handler := func(name string, code int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = append(called, name)
+			if code != 0 {
+				w.WriteHeader(code)
+				_, _ = fmt.Fprint(w, name)
+			}
+		})
+	}
+
+	for _, c := range []struct {
+		name       string
+		handlers   []http.Handler
+		wantCalled []string
+		wantCode   int
+		wantBody   string
+	}{
+		{
+			"first no-ops",
+			[]http.Handler{handler("a", 0), handler("b", 201)},
+			[]string{"a", "b"},
+			201,
+			"b",
+		},
+		{
+			"first responds",
+			[]http.Handler{handler("a", 202), handler("b", 201)},
+			[]string{"a"},
+			202,
+			"a",
+		},
+		{
+			"none respond",
+			[]http.Handler{handler("a", 0), handler("b", 0)},
+			[]string{"a", "b"},
+			200,
+			"",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			called = nil
+
+			w := httptest.NewRecorder()
+			rte.FirstOf(c.handlers...).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			if !reflect.DeepEqual(called, c.wantCalled) {
+				t.Fatalf("want called %v but got %v", c.wantCalled, called)
+			}
+			if w.Code != c.wantCode || w.Body.String() != c.wantBody {
+				t.Fatalf("want %v %q but got %v %q", c.wantCode, c.wantBody, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("informational", func(t *testing.T) {
+		// a recorder reports an informational status as the response's, so serve it for real
+		srv := httptest.NewServer(rte.FirstOf(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusEarlyHints)
+			}),
+			handler("b", 201),
+		))
+		defer srv.Close()
+
+		resp, err := srv.Client().Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != 201 {
+			t.Fatalf("want 201 but got %v", resp.StatusCode)
+		}
+	})
+}
+
+func TestRedirects(t *testing.T) {
+	tbl := rte.Must(rte.Redirects(map[string]string{
+		"/old":       "/new",
+		"/older/foo": "https://example.com/foo",
+	}, http.StatusMovedPermanently))
+
+	for _, c := range []struct {
+		method, path, location string
+	}{
+		{"GET", "/old", "/new"},
+		{"HEAD", "/old", "/new"},
+		{"GET", "/older/foo", "https://example.com/foo"},
+	} {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+			if w.Code != http.StatusMovedPermanently {
+				t.Fatalf("Wanted 301 but got %v", w.Code)
+			}
+			if loc := w.Header().Get("Location"); loc != c.location {
+				t.Fatalf("Wanted location %q but got %q", c.location, loc)
+			}
+		})
+	}
+
+	t.Run("collision", func(t *testing.T) {
+		_, err := rte.New(append(
+			rte.Routes("GET /old", func(w http.ResponseWriter, r *http.Request) {}),
+			rte.Redirects(map[string]string{"/old": "/new"}, http.StatusFound)...,
+		))
+		if err == nil || err.(*rte.TableError).Type != rte.ErrTypeDuplicateHandler {
+			t.Fatalf("Wanted duplicate handler error but got %v", err)
+		}
+	})
+}
+
+func TestCatchAllExcept(t *testing.T) {
+	index := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "index")
+	})
+	tbl := rte.Must(
+		rte.Routes("GET /api/users", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "users")
+		}),
+		rte.CatchAllExcept(index, "/api/", "/static/"),
+	)
+
+	for _, c := range []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"/dashboard", 200, "index"},
+		{"/dashboard/settings", 200, "index"},
+		{"/", 200, "index"},
+		{"/api", 200, "index"},
+		{"/api/users", 200, "users"},
+		{"/api/unknown", 404, "404 page not found\n"},
+		{"/static/app.js", 404, "404 page not found\n"},
+		{"/%61pi/unknown", 404, "404 page not found\n"},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if w.Code != c.wantCode || w.Body.String() != c.wantBody {
+				t.Fatalf("want %v %q but got %v %q", c.wantCode, c.wantBody, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSPAFallback(t *testing.T) {
+	index := filepath.Join(t.TempDir(), "index.html")
+	if err := ioutil.WriteFile(index, []byte("<html>app</html>"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl := rte.Must(rte.Routes("GET /api/users", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "users")
+	}))
+	tbl.Default = tbl.SPAFallback(index)
+
+	for _, c := range []struct {
+		name, method, path, accept string
+		wantCode                   int
+		wantBody                   string
+	}{
+		{"navigation", "GET", "/app/dashboard/settings", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8", 200, "<html>app</html>"},
+		{"asset", "GET", "/assets/app.js", "*/*", 404, "404 page not found\n"},
+		{"post navigation", "POST", "/app/dashboard/settings", "text/html", 404, "404 page not found\n"},
+		{"matched", "GET", "/api/users", "text/html", 200, "users"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.method, c.path, nil)
+			r.Header.Set("Accept", c.accept)
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, r)
+			if w.Code != c.wantCode || w.Body.String() != c.wantBody {
+				t.Fatalf("Wanted %v %q but got %v %q", c.wantCode, c.wantBody, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+type orderStatus string
+
+const (
+	orderPending orderStatus = "pending"
+	orderShipped orderStatus = "shipped"
+)
+
+func TestEnum(t *testing.T) {
+	tbl := rte.Must(rte.Routes(
+		"GET /orders/:status", rte.Enum(
+			[]orderStatus{orderPending, orderShipped},
+			func(w http.ResponseWriter, r *http.Request, s orderStatus) {
+				_, _ = fmt.Fprintf(w, "status %v", s)
+			},
+		),
+	))
+
+	for _, c := range []struct {
+		path, want string
+		code       int
+	}{
+		{"/orders/pending", "status pending", 200},
+		{"/orders/shipped", "status shipped", 200},
+		{"/orders/lost", "404 page not found\n", 404},
+		{"/orders/Pending", "404 page not found\n", 404},
+		{"/orders/", "404 page not found\n", 404},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if w.Code != c.code || w.Body.String() != c.want {
+				t.Fatalf("Wanted %v %q but got %v %q", c.code, c.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		tbl.Default = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusGone)
+		})
+		tbl.DefaultByPrefix = []rte.PrefixHandler{{
+			Prefix: "/orders/",
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			}),
+		}}
+
+		w := httptest.NewRecorder()
+		tbl.ServeHTTP(w, httptest.NewRequest("GET", "/orders/lost", nil))
+		if w.Code != http.StatusTeapot {
+			t.Fatalf("Wanted %v but got %v", http.StatusTeapot, w.Code)
+		}
+
+		tbl.DefaultByPrefix = nil
+		w = httptest.NewRecorder()
+		tbl.ServeHTTP(w, httptest.NewRequest("GET", "/orders/lost", nil))
+		if w.Code != http.StatusGone {
+			t.Fatalf("Wanted %v but got %v", http.StatusGone, w.Code)
+		}
+	})
+}
+
+func TestAllMethods(t *testing.T) {
+	tbl := rte.Must(rte.Routes(
+		rte.AllMethods("/debug/echo", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, r.Method)
+		}),
+	))
+
+	for _, method := range []string{"GET", "POST", "PROPFIND"} {
+		t.Run(method, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest(method, "/debug/echo", nil))
+			if w.Code != 200 || w.Body.String() != method {
+				t.Fatalf("Wanted 200 %q but got %v %q", method, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if r := rte.AllMethods("/foo", http.NotFoundHandler()); r.Method != rte.MethodAny || r.Path != "/foo" {
+		t.Fatalf("Wanted an equivalent MethodAny route but got %v", r)
+	}
+}
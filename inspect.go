@@ -0,0 +1,439 @@
+package rte
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Explain describes how a request with the provided method and path would be matched: each node of the routing tree
+// which is reached, which branch (static or wildcard) is taken at each step and why, the route matched (if any), and
+// any other routes which would also have matched the request but are shadowed. Wildcard segments appear as '*' in
+// the tree. It's intended as a debugging aid for when a route isn't matching as expected; the format of the
+// explanation isn't stable.
+func (t *Table) Explain(method, path string) string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "%v %v\n", method, path)
+
+	flag, mask := t.acceptMethods(method)
+	if mask == 0 {
+		b.WriteString("no routes accept the method\n")
+		return b.String()
+	}
+
+	var variables PathVars
+	e := explainer{b: &b, methodMask: mask, path: t.cleanPath(path), vars: variables[:], depths: make(map[*node]int)}
+	_, n := t.matchPath(mask, e.path, e.vars, e)
+
+	var winner *methodHandler
+	if winner, _ = t.handlerFor(n, method, flag); winner != nil {
+		_, _ = fmt.Fprintf(&b, "matched route %d: %v\n", winner.Idx, winner.Route)
+	} else {
+		b.WriteString("no route matched\n")
+	}
+
+	for _, mh := range t.handlers() {
+		if mh.handler == winner || mh.handler.Flag&mask == 0 {
+			continue
+		}
+		if vars, ok := patternVars(mh.pattern, e.path, t.sep, mh.fold); !ok ||
+			(mh.handler.lengths != nil && !fits(mh.handler.lengths, vars)) {
+			continue
+		}
+		_, _ = fmt.Fprintf(&b, "shadowed route %d: %v\n", mh.handler.Idx, mh.handler.Route)
+	}
+
+	return b.String()
+}
+
+// explainer is a matchTracer describing each step of the matcher
+type explainer struct {
+	b          *strings.Builder
+	methodMask uint
+	path       string
+	// vars are populated by the matcher
+	vars []string
+	// depths are the indentation of the nodes' descriptions; the roots' are zero
+	depths map[*node]int
+}
+
+func (e explainer) printf(depth int, format string, args ...interface{}) {
+	e.b.WriteString(strings.Repeat("  ", depth))
+	_, _ = fmt.Fprintf(e.b, format, args...)
+	e.b.WriteByte('\n')
+}
+
+func (e explainer) branch(parent *node, pathIdx int, first, second *node) {
+	depth := e.depths[parent] + 1
+	e.depths[first], e.depths[second] = depth, depth
+
+	switch {
+	case first == nil:
+		e.printf(depth, "no branch for %q", e.path[pathIdx:])
+	case second == nil:
+		e.printf(depth, "%v branch %q", branchKind(first), first.label)
+	case branchKind(first) == "wildcard":
+		e.printf(depth, "wildcard branch %q preferred to static branch %q due to priority", first.label, second.label)
+	default:
+		e.printf(depth, "static branch %q preferred to wildcard branch %q", first.label, second.label)
+	}
+}
+
+func (e explainer) backtrack(parent, first, second *node, matched bool) {
+	if matched {
+		e.printf(e.depths[parent]+1, "%v branch %q matched a lower priority route; trying %v branch %q",
+			branchKind(first), first.label, branchKind(second), second.label)
+		return
+	}
+	e.printf(e.depths[parent]+1, "%v branch %q failed; backtracking to %v branch %q",
+		branchKind(first), first.label, branchKind(second), second.label)
+}
+
+func (e explainer) visit(*node) {}
+
+func (e explainer) reject(n *node, pathIdx, lblIdx, numVars int) {
+	depth := e.depths[n] + 1
+	switch {
+	case lblIdx < len(n.label) && pathIdx == len(e.path):
+		e.printf(depth, "path ended within %q", n.label)
+	case lblIdx < len(n.label):
+		e.printf(depth, "%q does not match %q", e.path[pathIdx:], n.label[lblIdx:])
+	case n.own&e.methodMask == 0:
+		e.printf(depth, "path ended at %q but it has no route for the method", n.label)
+	default:
+		e.printf(depth, "path ended at %q but variables %q don't satisfy the length constraints", n.label, e.vars[:numVars])
+	}
+}
+
+func (e explainer) fold() {
+	e.b.WriteString("trying case insensitive routes\n")
+}
+
+// branchKind describes a child node by whether it's the wildcard
+func branchKind(n *node) string {
+	if n.label[0] == '*' {
+		return "wildcard"
+	}
+	return "static"
+}
+
+type patternHandler struct {
+	pattern string
+	handler *methodHandler
+	// fold indicates the pattern is case insensitive
+	fold bool
+}
+
+// handlers enumerates every handler in the table
+func (t *Table) handlers() []patternHandler {
+	found := handlers(t.root, "", false)
+	if t.foldRoot != nil {
+		found = append(found, handlers(t.foldRoot, "", true)...)
+	}
+	return found
+}
+
+// handlers enumerates every handler in the subtree along with the normalized pattern it's registered at
+func handlers(n *node, prefix string, fold bool) (found []patternHandler) {
+	prefix += n.label
+	for i := range n.hndlrs {
+		found = append(found, patternHandler{prefix, &n.hndlrs[i], fold})
+	}
+	for _, c := range n.children {
+		found = append(found, handlers(c, prefix, fold)...)
+	}
+	return
+}
+
+// patternVars matches the path against the normalized pattern in isolation, returning its variables
+func patternVars(pattern, path string, sep byte, fold bool) ([]string, bool) {
+	var (
+		vars    []string
+		pathIdx int
+	)
+	for i := 0; i < len(pattern); i++ {
+		if pathIdx == len(path) {
+			return nil, false
+		}
+		if pattern[i] == '*' {
+			wcStart := pathIdx
+			for pathIdx < len(path) && path[pathIdx] != sep {
+				pathIdx++
+			}
+			vars = append(vars, path[wcStart:pathIdx])
+			continue
+		}
+		if c := path[pathIdx]; c != pattern[i] && (!fold || lowerASCII(c) != pattern[i]) {
+			return nil, false
+		}
+		pathIdx++
+	}
+	return vars, pathIdx == len(path)
+}
+
+// WorstCaseDepth returns the greatest number of tree nodes a single lookup could visit. A static child is chosen by
+// the next byte of the path, so at most one of them is visited, but when a node has both a static child and a wildcard
+// child the matcher may backtrack from one to the other (see Route.Priority), visiting both. Each node is entered at
+// a single, fixed position within the path, so no node is visited more than once and the work per visit is bounded
+// by the length of the path -- i.e. matching is linear in the path length, with this as the constant.
+func (t *Table) WorstCaseDepth() int {
+	depth := worstCase(t.root) - 1 // the root isn't visited
+	if t.foldRoot != nil {
+		// the case insensitive routes are tried after the others
+		depth += worstCase(t.foldRoot) - 1
+	}
+	return depth
+}
+
+// Stats describes a Table as it was constructed; see NewWithStats.
+type Stats struct {
+	// Routes is the number of routes in the table; it's fewer than were provided if WithDuplicateResolver merged any
+	Routes int
+	// Nodes is the number of nodes in the routing tree, including those holding case insensitive routes
+	Nodes int
+	// WorstCaseDepth is as reported by Table.WorstCaseDepth
+	WorstCaseDepth int
+	// Duration is how long construction took
+	Duration time.Duration
+}
+
+// NewWithStats builds routes into a Table just as New does, also returning statistics about its construction -- e.g.
+// for startup diagnostics which detect a route set growing pathologically or construction slowing down.
+func NewWithStats(routes []Route, opts ...Option) (*Table, Stats, error) {
+	start := time.Now()
+	t, err := New(routes, opts...)
+	if err != nil {
+		return nil, Stats{}, err
+	}
+	duration := time.Since(start)
+
+	nodes := countNodes(t.root)
+	if t.foldRoot != nil {
+		nodes += countNodes(t.foldRoot)
+	}
+	return t, Stats{
+		Routes:         len(t.handlers()),
+		Nodes:          nodes,
+		WorstCaseDepth: t.WorstCaseDepth(),
+		Duration:       duration,
+	}, nil
+}
+
+// countNodes returns the number of nodes in the subtree
+func countNodes(n *node) int {
+	count := 1
+	for _, c := range n.children {
+		count += countNodes(c)
+	}
+	return count
+}
+
+func worstCase(n *node) int {
+	var static, wild int
+	for _, c := range n.children {
+		w := worstCase(c)
+		if c.label[0] == '*' {
+			wild = w
+		} else if w > static {
+			static = w
+		}
+	}
+	return 1 + static + wild
+}
+
+// RoutesUnderPrefix returns the routes, as provided to New, whose paths begin with the provided prefix, in the order
+// they were provided. Variables in the prefix match variables of any name -- e.g. "/users/:id/" matches
+// "/users/:user_id/posts" -- but the prefix otherwise needn't align with segment boundaries: "/api" matches both
+// "/api/users" and "/apis". Combined with ReloadableTable, it permits reloading a single module's routes while
+// leaving the others as they are.
+func (t *Table) RoutesUnderPrefix(prefix string) []Route {
+	normalized := normalize(prefix, t.sep)
+	found := under(t.root, normalized, false)
+	if t.foldRoot != nil {
+		found = append(found, under(t.foldRoot, toLowerASCII(normalized), true)...)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].handler.Idx < found[j].handler.Idx
+	})
+
+	var routes []Route
+	for _, f := range found {
+		routes = append(routes, f.handler.Route)
+	}
+	return routes
+}
+
+// under enumerates the handlers of the tree whose normalized patterns begin with the prefix
+func under(root *node, prefix string, fold bool) []patternHandler {
+	n, rest := root, prefix
+	for rest != "" {
+		c := n.child(rest[0])
+		if c == nil {
+			return nil
+		}
+		if len(c.label) >= len(rest) {
+			// the prefix ends within (or at the end of) this node's label
+			if !strings.HasPrefix(c.label, rest) {
+				return nil
+			}
+			n, rest = c, ""
+			continue
+		}
+		if !strings.HasPrefix(rest, c.label) {
+			return nil
+		}
+		n, rest = c, rest[len(c.label):]
+	}
+
+	return handlers(n, "", fold)
+}
+
+// HasPrefix reports whether any route's path begins with the provided prefix, which may be concrete -- e.g.
+// "/users/123/" matches "/users/:user_id/posts" -- or a template, in which case its variables match variables of any
+// name. As with RoutesUnderPrefix, the prefix needn't align with segment boundaries. It stops as soon as a route is
+// found, so it's cheaper than checking RoutesUnderPrefix for results.
+func (t *Table) HasPrefix(prefix string) bool {
+	normalized := normalize(prefix, t.sep)
+	if t.root.methods != 0 && hasPrefix(t.root, normalized, t.sep, false) {
+		return true
+	}
+	return t.foldRoot != nil && hasPrefix(t.foldRoot, normalized, t.sep, true)
+}
+
+// hasPrefix reports whether the subtree contains a pattern beginning with the normalized prefix; every node has a
+// handler within its subtree, so it's enough to consume the prefix
+func hasPrefix(n *node, prefix string, sep byte, fold bool) bool {
+	for i := 0; i < len(n.label); i++ {
+		if prefix == "" {
+			return true
+		}
+
+		if n.label[i] == '*' {
+			if prefix[0] == '*' {
+				prefix = prefix[1:]
+				continue
+			}
+			// a concrete segment matches a variable if it's not empty
+			j := 0
+			for j < len(prefix) && prefix[j] != sep {
+				j++
+			}
+			if j == 0 {
+				return false
+			}
+			prefix = prefix[j:]
+			continue
+		}
+
+		if c := prefix[0]; c != n.label[i] && (!fold || lowerASCII(c) != n.label[i]) {
+			return false
+		}
+		prefix = prefix[1:]
+	}
+
+	if prefix == "" {
+		return true
+	}
+
+	c := prefix[0]
+	if fold {
+		c = lowerASCII(c)
+	}
+	if static := n.child(c); c != '*' && static != nil && hasPrefix(static, prefix, sep, fold) {
+		return true
+	}
+	wild := n.child('*')
+	return wild != nil && hasPrefix(wild, prefix, sep, fold)
+}
+
+// StaticPaths returns the distinct paths of the routes without variables, for any method, in the order they were
+// provided to New -- e.g. for building a sitemap or warming a cache.
+func (t *Table) StaticPaths() []string {
+	found := t.handlers()
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].handler.Idx < found[j].handler.Idx
+	})
+
+	var (
+		paths []string
+		seen  = make(map[string]bool)
+	)
+	for _, f := range found {
+		if p := f.handler.Route.Path; !strings.Contains(f.pattern, "*") && !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// RawHandler returns the handler of the route matching the method and path (as it would be sent, i.e. escaped)
+// without any of the route's middleware -- including that added by Wrap -- or other per-route behavior, e.g.
+// Validate, and whether or not a route matched. The handler is bound to the path's variables, so it ignores the
+// path of the requests it serves. It's intended for testing a handler's logic in isolation.
+func (t *Table) RawHandler(method, path string) (http.Handler, bool) {
+	var variables PathVars
+	n, mh := t.matchVars(method, t.cleanPath(path), variables[:])
+	if mh == nil {
+		return nil, false
+	}
+	if t.decodesVars() {
+		for i, v := range variables[:n] {
+			unescaped, err := t.unescape(v)
+			if err != nil {
+				return nil, false
+			}
+			variables[i] = unescaped
+		}
+	}
+
+	raw := mh.raw
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw(w, r, variables)
+	}), true
+}
+
+// MatchCounts returns the number of requests dispatched to each route, keyed by the route's pattern (e.g.
+// "GET /foo/:foo_id"), suffixed with " (case insensitive)" if it's CaseInsensitive so that it's distinct from a case
+// sensitive route with the same pattern; routes which have never been matched are included with a count of zero. It
+// returns nil unless the table was built with WithMatchCounters.
+func (t *Table) MatchCounts() map[string]int64 {
+	if !t.countMatches {
+		return nil
+	}
+
+	counts := make(map[string]int64)
+	for _, f := range t.handlers() {
+		key := f.handler.Route.String()
+		if f.fold {
+			key += " (case insensitive)"
+		}
+		counts[key] = f.handler.count.Load()
+	}
+	return counts
+}
+
+// Doc returns the Doc of the route matching the method and path (as it would be sent, i.e. escaped); it's empty if no
+// route matches or the route has no Doc.
+func (t *Table) Doc(method, path string) string {
+	var variables PathVars
+	_, mh := t.matchVars(method, t.cleanPath(path), variables[:])
+	if mh == nil {
+		return ""
+	}
+	return mh.Route.Doc
+}
+
+// MiddlewareDepth returns the number of middleware layers wrapping the handler of the route matching the method and
+// path (as it would be sent, i.e. escaped), or -1 if no route matches. Each middleware composed by Wrap or Compose
+// counts as a layer, so tests can assert that a route doesn't accumulate unexpected middleware.
+func (t *Table) MiddlewareDepth(method, path string) int {
+	var variables PathVars
+	_, mh := t.matchVars(method, t.cleanPath(path), variables[:])
+	if mh == nil {
+		return -1
+	}
+	return middlewareDepth(mh.Route.Middleware)
+}
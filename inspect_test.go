@@ -0,0 +1,446 @@
+package rte_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jwilner/rte"
+)
+
+func TestExplain(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	h1 := func(http.ResponseWriter, *http.Request, string) {}
+	tbl := rte.Must([]rte.Route{
+		{Method: "GET", Path: "/users/me", Handler: h, Priority: 1},
+		{Method: "GET", Path: "/users/:id", Handler: h1},
+		{Method: "POST", Path: "/users", Handler: h},
+	})
+
+	for _, c := range []struct {
+		name, method, path, want string
+	}{
+		{
+			name:   "shadowed",
+			method: "GET",
+			path:   "/users/me",
+			want: `GET /users/me
+  static branch "/users"
+    static branch "/"
+      static branch "me" preferred to wildcard branch "*"
+matched route 0: GET /users/me
+shadowed route 1: GET /users/:id
+`,
+		},
+		{
+			name:   "backtracked",
+			method: "GET",
+			path:   "/users/mine",
+			want: `GET /users/mine
+  static branch "/users"
+    static branch "/"
+      static branch "me" preferred to wildcard branch "*"
+        "ine" does not match "e"
+      static branch "me" failed; backtracking to wildcard branch "*"
+matched route 1: GET /users/:id
+`,
+		},
+		{
+			name:   "no route",
+			method: "POST",
+			path:   "/users/me",
+			want: `POST /users/me
+  static branch "/users"
+    no branch for "/me"
+no route matched
+`,
+		},
+		{
+			name:   "unknown method",
+			method: "DELETE",
+			path:   "/users",
+			want: `DELETE /users
+no routes accept the method
+`,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := tbl.Explain(c.method, c.path); got != c.want {
+				t.Fatalf("want:\n%v\ngot:\n%v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestExplainConstraints(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	h1 := func(http.ResponseWriter, *http.Request, string) {}
+	tbl := rte.Must([]rte.Route{
+		{Method: "GET", Path: "/codes/new", Handler: h, Priority: 1},
+		{Method: "GET", Path: "/codes/:code{4}", Handler: h1},
+	})
+	tbl.StripMatrixParams = true
+
+	for _, c := range []struct {
+		name, path, want string
+	}{
+		{
+			name: "not shadowed",
+			path: "/codes/new",
+			want: `GET /codes/new
+  static branch "/codes/"
+    static branch "new" preferred to wildcard branch "*"
+matched route 0: GET /codes/new
+`,
+		},
+		{
+			name: "length",
+			path: "/codes/abc",
+			want: `GET /codes/abc
+  static branch "/codes/"
+    wildcard branch "*"
+      path ended at "*" but variables ["abc"] don't satisfy the length constraints
+no route matched
+`,
+		},
+		{
+			name: "cleaned",
+			path: "/codes;v=1/abcd",
+			want: `GET /codes;v=1/abcd
+  static branch "/codes/"
+    wildcard branch "*"
+matched route 1: GET /codes/:code{4}
+`,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := tbl.Explain("GET", c.path); got != c.want {
+				t.Fatalf("want:\n%v\ngot:\n%v", c.want, got)
+			}
+		})
+	}
+}
+
+// pathologicalRoutes forces backtracking at every level
+func pathologicalRoutes() []rte.Route {
+	h := func(http.ResponseWriter, *http.Request) {}
+	return []rte.Route{
+		{Method: "GET", Path: "/:a/:b/:c/:d", Handler: h},
+		{Method: "GET", Path: "/x/:b/:c/:d/z", Handler: h, Priority: 1},
+		{Method: "GET", Path: "/x/x/:c/:d/z", Handler: h, Priority: 2},
+		{Method: "GET", Path: "/x/x/x/:d/z", Handler: h, Priority: 3},
+		{Method: "GET", Path: "/x/x/x/x/z", Handler: h, Priority: 4},
+	}
+}
+
+func TestWorstCaseDepth(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		routes []rte.Route
+		want   int
+	}{
+		{"empty", nil, 0},
+		{"static", rte.Routes("GET /foo", func(http.ResponseWriter, *http.Request) {}), 1},
+		{
+			"no backtracking",
+			rte.Routes(
+				"GET /foo/bar", func(http.ResponseWriter, *http.Request) {},
+				"GET /foo/baz", func(http.ResponseWriter, *http.Request) {},
+				"GET /qux/:id", func(http.ResponseWriter, *http.Request, string) {},
+			),
+			3,
+		},
+		{"pathological", pathologicalRoutes(), 9},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := rte.Must(c.routes).WorstCaseDepth(); got != c.want {
+				t.Fatalf("want %v but got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestRoutesUnderPrefix(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	h1 := func(http.ResponseWriter, *http.Request, string) {}
+	tbl := rte.Must(rte.Routes(
+		"GET /api/users", h,
+		"GET /health", h,
+		"POST /api/users", h,
+		"GET /api/users/:user_id", h1,
+		"GET /apis", h,
+		"GET /", h,
+		"DELETE /api/users/:user_id", h1,
+	))
+
+	for _, c := range []struct {
+		prefix string
+		want   []string
+	}{
+		{"/api/", []string{"GET /api/users", "POST /api/users", "GET /api/users/:user_id", "DELETE /api/users/:user_id"}},
+		{"/api", []string{"GET /api/users", "POST /api/users", "GET /api/users/:user_id", "GET /apis", "DELETE /api/users/:user_id"}},
+		{"/api/users/:id", []string{"GET /api/users/:user_id", "DELETE /api/users/:user_id"}},
+		{"/api/us", []string{"GET /api/users", "POST /api/users", "GET /api/users/:user_id", "DELETE /api/users/:user_id"}},
+		{"/health", []string{"GET /health"}},
+		{"/healthz", nil},
+		{"/other", nil},
+		{"", []string{"GET /api/users", "GET /health", "POST /api/users", "GET /api/users/:user_id", "GET /apis", "GET /", "DELETE /api/users/:user_id"}},
+	} {
+		t.Run(c.prefix, func(t *testing.T) {
+			var got []string
+			for _, r := range tbl.RoutesUnderPrefix(c.prefix) {
+				got = append(got, r.String())
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("want %q but got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestHasPrefix(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	h1 := func(http.ResponseWriter, *http.Request, string) {}
+	tbl := rte.Must(append(
+		rte.Routes(
+			"GET /api/users", h,
+			"GET /api/users/:user_id/posts", h1,
+			"GET /apis", h,
+		),
+		rte.Route{Method: "GET", Path: "/Docs/Intro", Handler: h, CaseInsensitive: true},
+	))
+
+	for _, c := range []struct {
+		prefix string
+		want   bool
+	}{
+		{"", true},
+		{"/api/", true},
+		{"/api/us", true},
+		{"/api/users/", true},
+		{"/api/users/123", true},
+		{"/api/users/123/po", true},
+		{"/api/users/:id/posts", true},
+		{"/api/users/123/comments", false},
+		{"/api/users//posts", false},
+		{"/apis/", false},
+		{"/admin", false},
+		{"/docs/in", true},
+		{"/DOCS/INTRO", true},
+		{"/docs/outro", false},
+	} {
+		t.Run(c.prefix, func(t *testing.T) {
+			if got := tbl.HasPrefix(c.prefix); got != c.want {
+				t.Fatalf("want %v but got %v", c.want, got)
+			}
+		})
+	}
+
+	if rte.Must(nil).HasPrefix("") {
+		t.Fatal("expected an empty table to have no prefixes")
+	}
+}
+
+func TestStaticPaths(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	h1 := func(http.ResponseWriter, *http.Request, string) {}
+	tbl := rte.Must(append(
+		rte.Routes(
+			"GET /about", h,
+			"GET /users/:user_id", h1,
+			"GET /", h,
+			"POST /about", h,
+			"GET /posts/:id.:format", func(http.ResponseWriter, *http.Request, string, string) {},
+			"GET /signup/new", h,
+		),
+		rte.Route{Method: "GET", Path: "/Docs/Intro", Handler: h, CaseInsensitive: true},
+	))
+
+	if got, want := tbl.StaticPaths(), []string{"/about", "/", "/signup/new", "/Docs/Intro"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %q but got %q", want, got)
+	}
+}
+
+func TestRawHandler(t *testing.T) {
+	var calls []string
+	mw := func(name string) rte.Middleware {
+		return rte.MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			calls = append(calls, name)
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+	}
+	tbl := rte.Must(rte.Wrap(mw("auth"), []rte.Route{{
+		Method: "GET",
+		Path:   "/users/:user_id/posts/:id.:format",
+		Handler: func(w http.ResponseWriter, r *http.Request, vars [3]string) {
+			calls = append(calls, "handler")
+			_, _ = fmt.Fprintf(w, "%q", vars)
+		},
+		Middleware: mw("logging"),
+		Validate: func(r *http.Request) error {
+			return errors.New("invalid")
+		},
+	}}))
+
+	w := httptest.NewRecorder()
+	tbl.ServeHTTP(w, httptest.NewRequest("GET", "/users/abc/posts/123.json", nil))
+	if w.Code != 401 || !reflect.DeepEqual(calls, []string{"auth"}) {
+		t.Fatalf("want the middleware to respond but got %v %q", w.Code, calls)
+	}
+
+	calls = nil
+	h, ok := tbl.RawHandler("GET", "/users/abc/posts/123.json")
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 200 || w.Body.String() != `["abc" "123" "json"]` || !reflect.DeepEqual(calls, []string{"handler"}) {
+		t.Fatalf("want the handler alone to respond but got %v %q %q", w.Code, w.Body.String(), calls)
+	}
+
+	if _, ok := tbl.RawHandler("POST", "/users/abc/posts/123.json"); ok {
+		t.Fatal("expected no match for another method")
+	}
+}
+
+func TestMatchCounts(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	routes := append(rte.Routes(
+		"GET /foo", h,
+		"POST /foo", h,
+		"GET /foo/:foo_id", func(http.ResponseWriter, *http.Request, string) {},
+		"GET /unused", h,
+	), rte.Route{Method: "GET", Path: "/foo", Handler: h, CaseInsensitive: true})
+
+	tbl := rte.Must(routes, rte.WithMatchCounters())
+	for _, r := range []struct{ method, path string }{
+		{"GET", "/foo"},
+		{"GET", "/foo"},
+		{"POST", "/foo"},
+		{"GET", "/foo/abc"},
+		{"GET", "/foo/def"},
+		{"GET", "/foo/ghi"},
+		{"GET", "/FOO"},
+		{"GET", "/missing"},
+	} {
+		tbl.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.path, nil))
+	}
+
+	want := map[string]int64{
+		"GET /foo":                    2,
+		"POST /foo":                   1,
+		"GET /foo/:foo_id":            3,
+		"GET /unused":                 0,
+		"GET /foo (case insensitive)": 1,
+	}
+	if got := tbl.MatchCounts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v but got %v", want, got)
+	}
+
+	if got := rte.Must(routes).MatchCounts(); got != nil {
+		t.Fatalf("want no counts when disabled but got %v", got)
+	}
+}
+
+func TestMiddlewareDepth(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	tbl := rte.Must(append(
+		rte.Wrap(stringMW("1"), rte.Wrap(stringMW("2"), rte.Wrap(stringMW("3"), rte.Routes("GET /wrapped", h)))),
+		rte.Route{Method: "GET", Path: "/composed", Handler: h, Middleware: rte.Compose(stringMW("1"), stringMW("2"))},
+		rte.Route{Method: "GET", Path: "/bare", Handler: h},
+	))
+
+	for _, c := range []struct {
+		path string
+		want int
+	}{
+		{"/wrapped", 3},
+		{"/composed", 2},
+		{"/bare", 0},
+		{"/missing", -1},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			if got := tbl.MiddlewareDepth("GET", c.path); got != c.want {
+				t.Fatalf("want %v but got %v", c.want, got)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	tbl.ServeHTTP(w, httptest.NewRequest("GET", "/wrapped", nil))
+	if w.Body.String() != "1\n2\n3\n" {
+		t.Fatalf("want %q but got %q", "1\n2\n3\n", w.Body.String())
+	}
+}
+
+func TestDoc(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	tbl := rte.Must([]rte.Route{
+		{Method: "GET", Path: "/users", Handler: h, Doc: "Lists the users"},
+		{Method: "GET", Path: "/users/:user_id", Handler: h, Doc: "Shows a user"},
+		{Method: "DELETE", Path: "/users/:user_id", Handler: h},
+	})
+
+	for _, c := range []struct {
+		method, path, want string
+	}{
+		{"GET", "/users", "Lists the users"},
+		{"GET", "/users/123", "Shows a user"},
+		{"DELETE", "/users/123", ""},
+		{"GET", "/missing", ""},
+	} {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			if got := tbl.Doc(c.method, c.path); got != c.want {
+				t.Fatalf("want %q but got %q", c.want, got)
+			}
+		})
+	}
+
+	var help []string
+	for _, r := range tbl.RoutesUnderPrefix("/") {
+		if r.Doc != "" {
+			help = append(help, fmt.Sprintf("%v: %v", r, r.Doc))
+		}
+	}
+	if want := []string{"GET /users: Lists the users", "GET /users/:user_id: Shows a user"}; !reflect.DeepEqual(help, want) {
+		t.Fatalf("want %q but got %q", want, help)
+	}
+}
+
+func TestNewWithStats(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	routes := []rte.Route{
+		{Method: "GET", Path: "/", Handler: h},
+		{Method: "GET", Path: "/users", Handler: h},
+		{Method: "POST", Path: "/users", Handler: h},
+		{Method: "GET", Path: "/users/:user_id", Handler: h},
+		{Method: "GET", Path: "/uploads", Handler: h},
+		{Method: "GET", Path: "/Legacy", Handler: h, CaseInsensitive: true},
+	}
+
+	tbl, stats, err := rte.NewWithStats(routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Routes != len(routes) {
+		t.Fatalf("want %v routes but got %v", len(routes), stats.Routes)
+	}
+	// the two roots, "/", "u", "sers", "/*", "ploads", and "/legacy"
+	if stats.Nodes != 8 {
+		t.Fatalf("want 8 nodes but got %v", stats.Nodes)
+	}
+	if stats.WorstCaseDepth != tbl.WorstCaseDepth() {
+		t.Fatalf("want worst case depth %v but got %v", tbl.WorstCaseDepth(), stats.WorstCaseDepth)
+	}
+	if stats.Duration < 0 {
+		t.Fatalf("want a non-negative duration but got %v", stats.Duration)
+	}
+
+	if _, _, err := rte.NewWithStats(append(routes, routes[0])); err == nil {
+		t.Fatal("want an error for a duplicate route")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 )
 
 var (
@@ -21,6 +22,10 @@ type Signature struct {
 	Name  string
 	Arr   bool
 	Count int
+	// Ctx indicates that the handler takes the request's context.Context as its first parameter
+	Ctx bool
+	// Status indicates that the handler returns a status code and an error rather than writing them itself
+	Status bool
 }
 
 func (s Signature) PNames() []string {
@@ -87,6 +92,20 @@ func generateDefaultSigs(maxVars int) []Signature {
 		}
 		signatures = append(signatures, Signature{Name: fmt.Sprintf("arrFunc%d", i), Count: i, Arr: true})
 	}
+
+	// every form also has a variant taking a leading context.Context
+	for _, s := range signatures {
+		s.Name = "ctx" + strings.ToUpper(s.Name[:1]) + s.Name[1:]
+		s.Ctx = true
+		signatures = append(signatures, s)
+	}
+
+	// ... and a variant returning (int, error)
+	for _, s := range signatures {
+		s.Name = "status" + strings.ToUpper(s.Name[:1]) + s.Name[1:]
+		s.Status = true
+		signatures = append(signatures, s)
+	}
 	return signatures
 }
 
@@ -13,6 +13,7 @@ var (
 package funcs
 
 import (
+	"context"
 	"net/http"
 )
 
@@ -26,22 +27,27 @@ type PathVars [maxVars]string
 // Handler is a handler function permitting no-allocation handling of path variables
 type Handler func(w http.ResponseWriter, r *http.Request, pathVars PathVars)
 
-// Convert converts the provided interface to a Handler if possible.
-func Convert(i interface{}) (Handler, int, bool) {
+// ErrorHandler responds to a request whose handler returned a non-nil error along with the status it returned
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, status int, err error)
+
+// Convert converts the provided interface to a Handler if possible. Every supported form may optionally take the
+// request's context.Context as its first parameter, and may optionally return an int status and an error, which are
+// handled by writeStatus; onErr receives any non-nil error.
+func Convert(i interface{}, onErr ErrorHandler) (Handler, int, bool) {
 	switch v := i.(type) {
 	case http.Handler:
 		return {{ .ZeroFuncName }}(v.ServeHTTP), 0, true
 {{- range $sig := .Signatures }}
 {{- if and (not .Arr) (gt .Count 0) }}
-	case func(w http.ResponseWriter, r *http.Request, {{ range $idx, $p := .PNames }}{{ if $idx }}, {{ end }}{{ $p }}{{ end }} string):
+	case func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request, {{ range $idx, $p := .PNames }}{{ if $idx }}, {{ end }}{{ $p }}{{ end }} string){{ if .Status }} (int, error){{ end }}:
 {{- else if eq .Count 0 }}
-	case func(w http.ResponseWriter, r *http.Request):
+	case func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request){{ if .Status }} (int, error){{ end }}:
 {{- else if eq .Count $.MaxVars }}
-	case func(w http.ResponseWriter, r *http.Request, pVars [maxVars]string):
+	case func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request, pVars [maxVars]string){{ if .Status }} (int, error){{ end }}:
 {{- else }}
-	case func(w http.ResponseWriter, r *http.Request, pVars [{{ .Count }}]string):
+	case func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request, pVars [{{ .Count }}]string){{ if .Status }} (int, error){{ end }}:
 {{- end }}
-		return {{ .Name }}(v), {{ .Count }}, true
+		return {{ .Name }}(v{{ if .Status }}, onErr{{ end }}), {{ .Count }}, true
 {{- end }}
 	default:
 		return nil, 0, false
@@ -52,33 +58,45 @@ func Convert(i interface{}) (Handler, int, bool) {
 
 {{ range $sig := .Signatures }}
 {{ if and (not .Arr) (gt .Count 0) }}
-// {{ .Name }} takes in a standard http handler also expecting {{ .Count }} path variable values and returns a valid bound handler
-func {{ .Name }}(f func(w http.ResponseWriter, r *http.Request, {{ range $idx, $p := .PNames }}{{ if $idx }}, {{ end }}{{ $p }}{{ end }} string)) Handler {
+// {{ .Name }} takes in a {{ if .Ctx }}context-first{{ else }}standard{{ end }} http handler{{ if .Status }} returning a status and an error,{{ end }} also expecting {{ .Count }} path variable values and returns a valid bound handler
+func {{ .Name }}(f func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request, {{ range $idx, $p := .PNames }}{{ if $idx }}, {{ end }}{{ $p }}{{ end }} string){{ if .Status }} (int, error), onErr ErrorHandler{{ end }}) Handler {
 	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
-		f(w, r, {{ range $idx, $el := .PNames }}{{ if $idx }}, {{ end }}pVars[{{ $idx }}]{{ end }})
+		{{ if .Status }}status, err := {{ end }}f({{ if .Ctx }}r.Context(), {{ end }}w, r, {{ range $idx, $el := .PNames }}{{ if $idx }}, {{ end }}pVars[{{ $idx }}]{{ end }})
+{{- if .Status }}
+		writeStatus(w, r, status, err, onErr)
+{{- end }}
     }
 }
 {{ else if eq .Count 0 }}
-// {{ .Name }} takes in a no path variable handler and returns a Handler fit for static paths
-func {{ .Name }}(f func(w http.ResponseWriter, r *http.Request)) Handler {
+// {{ .Name }} takes in a no path variable {{ if .Ctx }}context-first {{ end }}handler{{ if .Status }} returning a status and an error{{ end }} and returns a Handler fit for static paths
+func {{ .Name }}(f func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request){{ if .Status }} (int, error), onErr ErrorHandler{{ end }}) Handler {
 	return func(w http.ResponseWriter, r *http.Request, _ PathVars) {
-		f(w, r)
+		{{ if .Status }}status, err := {{ end }}f({{ if .Ctx }}r.Context(), {{ end }}w, r)
+{{- if .Status }}
+		writeStatus(w, r, status, err, onErr)
+{{- end }}
 	}
 }
 {{ else if eq .Count $.MaxVars }}
-// {{ .Name }} takes in handler expecting array of {{ $.MaxVars }} path variable values and returns a valid handler
-func {{ .Name }}(f func(w http.ResponseWriter, r *http.Request, pVars [maxVars]string)) Handler {
+// {{ .Name }} takes in {{ if .Ctx }}context-first {{ end }}handler{{ if .Status }} returning a status and an error,{{ end }} expecting array of {{ $.MaxVars }} path variable values and returns a valid handler
+func {{ .Name }}(f func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request, pVars [maxVars]string){{ if .Status }} (int, error), onErr ErrorHandler{{ end }}) Handler {
 	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
-		f(w, r, [maxVars]string(pVars))
+		{{ if .Status }}status, err := {{ end }}f({{ if .Ctx }}r.Context(), {{ end }}w, r, [maxVars]string(pVars))
+{{- if .Status }}
+		writeStatus(w, r, status, err, onErr)
+{{- end }}
 	}
 }
 {{ else }}
-// {{ .Name }} takes in handler expecting array of {{ .Count }} path variable values and returns a valid handler
-func {{ .Name }}(f func(w http.ResponseWriter, r *http.Request, pVars [{{ .Count }}]string)) Handler {
+// {{ .Name }} takes in {{ if .Ctx }}context-first {{ end }}handler{{ if .Status }} returning a status and an error,{{ end }} expecting array of {{ .Count }} path variable values and returns a valid handler
+func {{ .Name }}(f func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request, pVars [{{ .Count }}]string){{ if .Status }} (int, error), onErr ErrorHandler{{ end }}) Handler {
 	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
 		var trimmed [{{ .Count }}]string
 		copy(trimmed[:], pVars[:])
-		f(w, r, trimmed)
+		{{ if .Status }}status, err := {{ end }}f({{ if .Ctx }}r.Context(), {{ end }}w, r, trimmed)
+{{- if .Status }}
+		writeStatus(w, r, status, err, onErr)
+{{- end }}
 	}
 }
 {{ end }}
@@ -12,6 +12,7 @@ var tmplTest = template.Must(template.New("").Parse(`
 package funcs_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -48,18 +49,30 @@ func TestFuncs(t *testing.T) {
 			Route:    "/{{ range $idx, $p := .PNames }}{{ if $idx }}/{{ end }}:var-{{ $p }}{{ end }}",
 			Path:     "/{{ range $idx, $p := .PNames }}{{ if $idx }}/{{ end }}{{ $p }}{{ end }}",
 {{- if and (not .Arr) (gt .Count 0) }}
-			Handler:  func(w http.ResponseWriter, r *http.Request, {{ range $idx, $p := .PNames }}{{ if $idx }}, {{ end }}{{ $p }}{{ end }} string) {
+			Handler:  func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request, {{ range $idx, $p := .PNames }}{{ if $idx }}, {{ end }}{{ $p }}{{ end }} string){{ if .Status }} (int, error){{ end }} {
+{{- if .Ctx }}
+				checkCtx(ctx, r)
+{{- end }}
 				_ = json.NewEncoder(w).Encode([]string {
 	{{- range $p := .PNames }}
 					{{ $p }},
 	{{- end }}
 				})
 {{- else if eq .Count 0 }}
-			Handler:  func(w http.ResponseWriter, r *http.Request) {
+			Handler:  func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request){{ if .Status }} (int, error){{ end }} {
+{{- if .Ctx }}
+				checkCtx(ctx, r)
+{{- end }}
 				_ = json.NewEncoder(w).Encode([]string {})
 {{- else }}
-			Handler:  func(w http.ResponseWriter, r *http.Request, pVars [{{ .Count }}]string) {
+			Handler:  func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request, pVars [{{ .Count }}]string){{ if .Status }} (int, error){{ end }} {
+{{- if .Ctx }}
+				checkCtx(ctx, r)
+{{- end }}
 				_ = json.NewEncoder(w).Encode(pVars)
+{{- end }}
+{{- if .Status }}
+				return 0, nil
 {{- end }}
 			},
 			Expected: "[{{ range $i, $p := .PNames }}{{ if $i }},{{ end }}\"{{ $p }}\"{{ end }}]\n",
@@ -95,6 +108,13 @@ func TestFuncs(t *testing.T) {
 	}
 }
 
+// checkCtx panics if the handler wasn't provided the request's context
+func checkCtx(ctx context.Context, r *http.Request) {
+	if ctx != r.Context() {
+		panic("handler wasn't passed the request context")
+	}
+}
+
 func BenchmarkFuncs(b *testing.B) {
 	for _, c := range []struct {
 		Name     string
@@ -109,11 +129,14 @@ func BenchmarkFuncs(b *testing.B) {
 			"/{{ range $idx, $p := .PNames }}{{ if $idx }}/{{ end }}:var-{{ $p }}{{ end }}",
 			"/{{ range $idx, $p := .PNames }}{{ if $idx }}/{{ end }}{{ $p }}{{ end }}",
 {{- if and (not .Arr) (gt .Count 0) }}
-			func(w http.ResponseWriter, r *http.Request, {{ range $idx, $p := .PNames }}{{ if $idx }}, {{ end }}{{ $p }}{{ end }} string) {
+			func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request, {{ range $idx, $p := .PNames }}{{ if $idx }}, {{ end }}{{ $p }}{{ end }} string){{ if .Status }} (int, error){{ end }} {
 {{- else if eq .Count 0 }}
-			func(w http.ResponseWriter, r *http.Request) {
+			func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request){{ if .Status }} (int, error){{ end }} {
 {{- else }}
-			func(w http.ResponseWriter, r *http.Request, pVars [{{ .Count }}]string) {
+			func({{ if .Ctx }}ctx context.Context, {{ end }}w http.ResponseWriter, r *http.Request, pVars [{{ .Count }}]string){{ if .Status }} (int, error){{ end }} {
+{{- end }}
+{{- if .Status }}
+				return 0, nil
 {{- end }}
 			},
 		},
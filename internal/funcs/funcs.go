@@ -2,6 +2,7 @@
 package funcs
 
 import (
+	"context"
 	"net/http"
 )
 
@@ -15,8 +16,13 @@ type PathVars [maxVars]string
 // Handler is a handler function permitting no-allocation handling of path variables
 type Handler func(w http.ResponseWriter, r *http.Request, pathVars PathVars)
 
-// Convert converts the provided interface to a Handler if possible.
-func Convert(i interface{}) (Handler, int, bool) {
+// ErrorHandler responds to a request whose handler returned a non-nil error along with the status it returned
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, status int, err error)
+
+// Convert converts the provided interface to a Handler if possible. Every supported form may optionally take the
+// request's context.Context as its first parameter, and may optionally return an int status and an error, which are
+// handled by writeStatus; onErr receives any non-nil error.
+func Convert(i interface{}, onErr ErrorHandler) (Handler, int, bool) {
 	switch v := i.(type) {
 	case http.Handler:
 		return func0(v.ServeHTTP), 0, true
@@ -46,6 +52,84 @@ func Convert(i interface{}) (Handler, int, bool) {
 		return arrFunc7(v), 7, true
 	case func(w http.ResponseWriter, r *http.Request, pVars [maxVars]string):
 		return arrFunc8(v), 8, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request):
+		return ctxFunc0(v), 0, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0 string):
+		return ctxFunc1(v), 1, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [1]string):
+		return ctxArrFunc1(v), 1, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1 string):
+		return ctxFunc2(v), 2, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [2]string):
+		return ctxArrFunc2(v), 2, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2 string):
+		return ctxFunc3(v), 3, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [3]string):
+		return ctxArrFunc3(v), 3, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string):
+		return ctxFunc4(v), 4, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [4]string):
+		return ctxArrFunc4(v), 4, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [5]string):
+		return ctxArrFunc5(v), 5, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [6]string):
+		return ctxArrFunc6(v), 6, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [7]string):
+		return ctxArrFunc7(v), 7, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [maxVars]string):
+		return ctxArrFunc8(v), 8, true
+	case func(w http.ResponseWriter, r *http.Request) (int, error):
+		return statusFunc0(v, onErr), 0, true
+	case func(w http.ResponseWriter, r *http.Request, p0 string) (int, error):
+		return statusFunc1(v, onErr), 1, true
+	case func(w http.ResponseWriter, r *http.Request, pVars [1]string) (int, error):
+		return statusArrFunc1(v, onErr), 1, true
+	case func(w http.ResponseWriter, r *http.Request, p0, p1 string) (int, error):
+		return statusFunc2(v, onErr), 2, true
+	case func(w http.ResponseWriter, r *http.Request, pVars [2]string) (int, error):
+		return statusArrFunc2(v, onErr), 2, true
+	case func(w http.ResponseWriter, r *http.Request, p0, p1, p2 string) (int, error):
+		return statusFunc3(v, onErr), 3, true
+	case func(w http.ResponseWriter, r *http.Request, pVars [3]string) (int, error):
+		return statusArrFunc3(v, onErr), 3, true
+	case func(w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string) (int, error):
+		return statusFunc4(v, onErr), 4, true
+	case func(w http.ResponseWriter, r *http.Request, pVars [4]string) (int, error):
+		return statusArrFunc4(v, onErr), 4, true
+	case func(w http.ResponseWriter, r *http.Request, pVars [5]string) (int, error):
+		return statusArrFunc5(v, onErr), 5, true
+	case func(w http.ResponseWriter, r *http.Request, pVars [6]string) (int, error):
+		return statusArrFunc6(v, onErr), 6, true
+	case func(w http.ResponseWriter, r *http.Request, pVars [7]string) (int, error):
+		return statusArrFunc7(v, onErr), 7, true
+	case func(w http.ResponseWriter, r *http.Request, pVars [maxVars]string) (int, error):
+		return statusArrFunc8(v, onErr), 8, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request) (int, error):
+		return statusCtxFunc0(v, onErr), 0, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0 string) (int, error):
+		return statusCtxFunc1(v, onErr), 1, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [1]string) (int, error):
+		return statusCtxArrFunc1(v, onErr), 1, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1 string) (int, error):
+		return statusCtxFunc2(v, onErr), 2, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [2]string) (int, error):
+		return statusCtxArrFunc2(v, onErr), 2, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2 string) (int, error):
+		return statusCtxFunc3(v, onErr), 3, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [3]string) (int, error):
+		return statusCtxArrFunc3(v, onErr), 3, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string) (int, error):
+		return statusCtxFunc4(v, onErr), 4, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [4]string) (int, error):
+		return statusCtxArrFunc4(v, onErr), 4, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [5]string) (int, error):
+		return statusCtxArrFunc5(v, onErr), 5, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [6]string) (int, error):
+		return statusCtxArrFunc6(v, onErr), 6, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [7]string) (int, error):
+		return statusCtxArrFunc7(v, onErr), 7, true
+	case func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [maxVars]string) (int, error):
+		return statusCtxArrFunc8(v, onErr), 8, true
 	default:
 		return nil, 0, false
 	}
@@ -157,3 +241,344 @@ func arrFunc8(f func(w http.ResponseWriter, r *http.Request, pVars [maxVars]stri
 		f(w, r, [maxVars]string(pVars))
 	}
 }
+
+// ctxFunc0 takes in a no path variable context-first handler and returns a Handler fit for static paths
+func ctxFunc0(f func(ctx context.Context, w http.ResponseWriter, r *http.Request)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, _ PathVars) {
+		f(r.Context(), w, r)
+	}
+}
+
+// ctxFunc1 takes in a context-first http handler also expecting 1 path variable values and returns a valid bound handler
+func ctxFunc1(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0 string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		f(r.Context(), w, r, pVars[0])
+	}
+}
+
+// ctxArrFunc1 takes in context-first handler expecting array of 1 path variable values and returns a valid handler
+func ctxArrFunc1(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [1]string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [1]string
+		copy(trimmed[:], pVars[:])
+		f(r.Context(), w, r, trimmed)
+	}
+}
+
+// ctxFunc2 takes in a context-first http handler also expecting 2 path variable values and returns a valid bound handler
+func ctxFunc2(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1 string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		f(r.Context(), w, r, pVars[0], pVars[1])
+	}
+}
+
+// ctxArrFunc2 takes in context-first handler expecting array of 2 path variable values and returns a valid handler
+func ctxArrFunc2(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [2]string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [2]string
+		copy(trimmed[:], pVars[:])
+		f(r.Context(), w, r, trimmed)
+	}
+}
+
+// ctxFunc3 takes in a context-first http handler also expecting 3 path variable values and returns a valid bound handler
+func ctxFunc3(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2 string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		f(r.Context(), w, r, pVars[0], pVars[1], pVars[2])
+	}
+}
+
+// ctxArrFunc3 takes in context-first handler expecting array of 3 path variable values and returns a valid handler
+func ctxArrFunc3(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [3]string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [3]string
+		copy(trimmed[:], pVars[:])
+		f(r.Context(), w, r, trimmed)
+	}
+}
+
+// ctxFunc4 takes in a context-first http handler also expecting 4 path variable values and returns a valid bound handler
+func ctxFunc4(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		f(r.Context(), w, r, pVars[0], pVars[1], pVars[2], pVars[3])
+	}
+}
+
+// ctxArrFunc4 takes in context-first handler expecting array of 4 path variable values and returns a valid handler
+func ctxArrFunc4(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [4]string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [4]string
+		copy(trimmed[:], pVars[:])
+		f(r.Context(), w, r, trimmed)
+	}
+}
+
+// ctxArrFunc5 takes in context-first handler expecting array of 5 path variable values and returns a valid handler
+func ctxArrFunc5(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [5]string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [5]string
+		copy(trimmed[:], pVars[:])
+		f(r.Context(), w, r, trimmed)
+	}
+}
+
+// ctxArrFunc6 takes in context-first handler expecting array of 6 path variable values and returns a valid handler
+func ctxArrFunc6(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [6]string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [6]string
+		copy(trimmed[:], pVars[:])
+		f(r.Context(), w, r, trimmed)
+	}
+}
+
+// ctxArrFunc7 takes in context-first handler expecting array of 7 path variable values and returns a valid handler
+func ctxArrFunc7(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [7]string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [7]string
+		copy(trimmed[:], pVars[:])
+		f(r.Context(), w, r, trimmed)
+	}
+}
+
+// ctxArrFunc8 takes in context-first handler expecting array of 8 path variable values and returns a valid handler
+func ctxArrFunc8(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [maxVars]string)) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		f(r.Context(), w, r, [maxVars]string(pVars))
+	}
+}
+
+// statusFunc0 takes in a no path variable handler returning a status and an error and returns a Handler fit for static paths
+func statusFunc0(f func(w http.ResponseWriter, r *http.Request) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, _ PathVars) {
+		status, err := f(w, r)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusFunc1 takes in a standard http handler returning a status and an error, also expecting 1 path variable values and returns a valid bound handler
+func statusFunc1(f func(w http.ResponseWriter, r *http.Request, p0 string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		status, err := f(w, r, pVars[0])
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusArrFunc1 takes in handler returning a status and an error, expecting array of 1 path variable values and returns a valid handler
+func statusArrFunc1(f func(w http.ResponseWriter, r *http.Request, pVars [1]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [1]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusFunc2 takes in a standard http handler returning a status and an error, also expecting 2 path variable values and returns a valid bound handler
+func statusFunc2(f func(w http.ResponseWriter, r *http.Request, p0, p1 string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		status, err := f(w, r, pVars[0], pVars[1])
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusArrFunc2 takes in handler returning a status and an error, expecting array of 2 path variable values and returns a valid handler
+func statusArrFunc2(f func(w http.ResponseWriter, r *http.Request, pVars [2]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [2]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusFunc3 takes in a standard http handler returning a status and an error, also expecting 3 path variable values and returns a valid bound handler
+func statusFunc3(f func(w http.ResponseWriter, r *http.Request, p0, p1, p2 string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		status, err := f(w, r, pVars[0], pVars[1], pVars[2])
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusArrFunc3 takes in handler returning a status and an error, expecting array of 3 path variable values and returns a valid handler
+func statusArrFunc3(f func(w http.ResponseWriter, r *http.Request, pVars [3]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [3]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusFunc4 takes in a standard http handler returning a status and an error, also expecting 4 path variable values and returns a valid bound handler
+func statusFunc4(f func(w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		status, err := f(w, r, pVars[0], pVars[1], pVars[2], pVars[3])
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusArrFunc4 takes in handler returning a status and an error, expecting array of 4 path variable values and returns a valid handler
+func statusArrFunc4(f func(w http.ResponseWriter, r *http.Request, pVars [4]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [4]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusArrFunc5 takes in handler returning a status and an error, expecting array of 5 path variable values and returns a valid handler
+func statusArrFunc5(f func(w http.ResponseWriter, r *http.Request, pVars [5]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [5]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusArrFunc6 takes in handler returning a status and an error, expecting array of 6 path variable values and returns a valid handler
+func statusArrFunc6(f func(w http.ResponseWriter, r *http.Request, pVars [6]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [6]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusArrFunc7 takes in handler returning a status and an error, expecting array of 7 path variable values and returns a valid handler
+func statusArrFunc7(f func(w http.ResponseWriter, r *http.Request, pVars [7]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [7]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusArrFunc8 takes in handler returning a status and an error, expecting array of 8 path variable values and returns a valid handler
+func statusArrFunc8(f func(w http.ResponseWriter, r *http.Request, pVars [maxVars]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		status, err := f(w, r, [maxVars]string(pVars))
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxFunc0 takes in a no path variable context-first handler returning a status and an error and returns a Handler fit for static paths
+func statusCtxFunc0(f func(ctx context.Context, w http.ResponseWriter, r *http.Request) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, _ PathVars) {
+		status, err := f(r.Context(), w, r)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxFunc1 takes in a context-first http handler returning a status and an error, also expecting 1 path variable values and returns a valid bound handler
+func statusCtxFunc1(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0 string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		status, err := f(r.Context(), w, r, pVars[0])
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxArrFunc1 takes in context-first handler returning a status and an error, expecting array of 1 path variable values and returns a valid handler
+func statusCtxArrFunc1(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [1]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [1]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(r.Context(), w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxFunc2 takes in a context-first http handler returning a status and an error, also expecting 2 path variable values and returns a valid bound handler
+func statusCtxFunc2(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1 string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		status, err := f(r.Context(), w, r, pVars[0], pVars[1])
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxArrFunc2 takes in context-first handler returning a status and an error, expecting array of 2 path variable values and returns a valid handler
+func statusCtxArrFunc2(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [2]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [2]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(r.Context(), w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxFunc3 takes in a context-first http handler returning a status and an error, also expecting 3 path variable values and returns a valid bound handler
+func statusCtxFunc3(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2 string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		status, err := f(r.Context(), w, r, pVars[0], pVars[1], pVars[2])
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxArrFunc3 takes in context-first handler returning a status and an error, expecting array of 3 path variable values and returns a valid handler
+func statusCtxArrFunc3(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [3]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [3]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(r.Context(), w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxFunc4 takes in a context-first http handler returning a status and an error, also expecting 4 path variable values and returns a valid bound handler
+func statusCtxFunc4(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		status, err := f(r.Context(), w, r, pVars[0], pVars[1], pVars[2], pVars[3])
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxArrFunc4 takes in context-first handler returning a status and an error, expecting array of 4 path variable values and returns a valid handler
+func statusCtxArrFunc4(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [4]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [4]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(r.Context(), w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxArrFunc5 takes in context-first handler returning a status and an error, expecting array of 5 path variable values and returns a valid handler
+func statusCtxArrFunc5(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [5]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [5]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(r.Context(), w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxArrFunc6 takes in context-first handler returning a status and an error, expecting array of 6 path variable values and returns a valid handler
+func statusCtxArrFunc6(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [6]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [6]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(r.Context(), w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxArrFunc7 takes in context-first handler returning a status and an error, expecting array of 7 path variable values and returns a valid handler
+func statusCtxArrFunc7(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [7]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		var trimmed [7]string
+		copy(trimmed[:], pVars[:])
+		status, err := f(r.Context(), w, r, trimmed)
+		writeStatus(w, r, status, err, onErr)
+	}
+}
+
+// statusCtxArrFunc8 takes in context-first handler returning a status and an error, expecting array of 8 path variable values and returns a valid handler
+func statusCtxArrFunc8(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [maxVars]string) (int, error), onErr ErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		status, err := f(r.Context(), w, r, [maxVars]string(pVars))
+		writeStatus(w, r, status, err, onErr)
+	}
+}
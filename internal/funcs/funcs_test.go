@@ -2,6 +2,7 @@
 package funcs_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -163,6 +164,451 @@ func TestFuncs(t *testing.T) {
 			},
 			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\",\"p5\",\"p6\",\"p7\"]\n",
 		},
+		{
+			Name:  "ctxFunc0",
+			Route: "/",
+			Path:  "/",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode([]string{})
+			},
+			Expected: "[]\n",
+		},
+		{
+			Name:  "ctxFunc1",
+			Route: "/:var-p0",
+			Path:  "/p0",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0 string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+				})
+			},
+			Expected: "[\"p0\"]\n",
+		},
+		{
+			Name:  "ctxArrFunc1",
+			Route: "/:var-p0",
+			Path:  "/p0",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [1]string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+			},
+			Expected: "[\"p0\"]\n",
+		},
+		{
+			Name:  "ctxFunc2",
+			Route: "/:var-p0/:var-p1",
+			Path:  "/p0/p1",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1 string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+					p1,
+				})
+			},
+			Expected: "[\"p0\",\"p1\"]\n",
+		},
+		{
+			Name:  "ctxArrFunc2",
+			Route: "/:var-p0/:var-p1",
+			Path:  "/p0/p1",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [2]string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+			},
+			Expected: "[\"p0\",\"p1\"]\n",
+		},
+		{
+			Name:  "ctxFunc3",
+			Route: "/:var-p0/:var-p1/:var-p2",
+			Path:  "/p0/p1/p2",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2 string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+					p1,
+					p2,
+				})
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\"]\n",
+		},
+		{
+			Name:  "ctxArrFunc3",
+			Route: "/:var-p0/:var-p1/:var-p2",
+			Path:  "/p0/p1/p2",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [3]string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\"]\n",
+		},
+		{
+			Name:  "ctxFunc4",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3",
+			Path:  "/p0/p1/p2/p3",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+					p1,
+					p2,
+					p3,
+				})
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\"]\n",
+		},
+		{
+			Name:  "ctxArrFunc4",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3",
+			Path:  "/p0/p1/p2/p3",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [4]string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\"]\n",
+		},
+		{
+			Name:  "ctxArrFunc5",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4",
+			Path:  "/p0/p1/p2/p3/p4",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [5]string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\"]\n",
+		},
+		{
+			Name:  "ctxArrFunc6",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5",
+			Path:  "/p0/p1/p2/p3/p4/p5",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [6]string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\",\"p5\"]\n",
+		},
+		{
+			Name:  "ctxArrFunc7",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6",
+			Path:  "/p0/p1/p2/p3/p4/p5/p6",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [7]string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\",\"p5\",\"p6\"]\n",
+		},
+		{
+			Name:  "ctxArrFunc8",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6/:var-p7",
+			Path:  "/p0/p1/p2/p3/p4/p5/p6/p7",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [8]string) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\",\"p5\",\"p6\",\"p7\"]\n",
+		},
+		{
+			Name:  "statusFunc0",
+			Route: "/",
+			Path:  "/",
+			Handler: func(w http.ResponseWriter, r *http.Request) (int, error) {
+				_ = json.NewEncoder(w).Encode([]string{})
+				return 0, nil
+			},
+			Expected: "[]\n",
+		},
+		{
+			Name:  "statusFunc1",
+			Route: "/:var-p0",
+			Path:  "/p0",
+			Handler: func(w http.ResponseWriter, r *http.Request, p0 string) (int, error) {
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+				})
+				return 0, nil
+			},
+			Expected: "[\"p0\"]\n",
+		},
+		{
+			Name:  "statusArrFunc1",
+			Route: "/:var-p0",
+			Path:  "/p0",
+			Handler: func(w http.ResponseWriter, r *http.Request, pVars [1]string) (int, error) {
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\"]\n",
+		},
+		{
+			Name:  "statusFunc2",
+			Route: "/:var-p0/:var-p1",
+			Path:  "/p0/p1",
+			Handler: func(w http.ResponseWriter, r *http.Request, p0, p1 string) (int, error) {
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+					p1,
+				})
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\"]\n",
+		},
+		{
+			Name:  "statusArrFunc2",
+			Route: "/:var-p0/:var-p1",
+			Path:  "/p0/p1",
+			Handler: func(w http.ResponseWriter, r *http.Request, pVars [2]string) (int, error) {
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\"]\n",
+		},
+		{
+			Name:  "statusFunc3",
+			Route: "/:var-p0/:var-p1/:var-p2",
+			Path:  "/p0/p1/p2",
+			Handler: func(w http.ResponseWriter, r *http.Request, p0, p1, p2 string) (int, error) {
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+					p1,
+					p2,
+				})
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\"]\n",
+		},
+		{
+			Name:  "statusArrFunc3",
+			Route: "/:var-p0/:var-p1/:var-p2",
+			Path:  "/p0/p1/p2",
+			Handler: func(w http.ResponseWriter, r *http.Request, pVars [3]string) (int, error) {
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\"]\n",
+		},
+		{
+			Name:  "statusFunc4",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3",
+			Path:  "/p0/p1/p2/p3",
+			Handler: func(w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string) (int, error) {
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+					p1,
+					p2,
+					p3,
+				})
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\"]\n",
+		},
+		{
+			Name:  "statusArrFunc4",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3",
+			Path:  "/p0/p1/p2/p3",
+			Handler: func(w http.ResponseWriter, r *http.Request, pVars [4]string) (int, error) {
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\"]\n",
+		},
+		{
+			Name:  "statusArrFunc5",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4",
+			Path:  "/p0/p1/p2/p3/p4",
+			Handler: func(w http.ResponseWriter, r *http.Request, pVars [5]string) (int, error) {
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\"]\n",
+		},
+		{
+			Name:  "statusArrFunc6",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5",
+			Path:  "/p0/p1/p2/p3/p4/p5",
+			Handler: func(w http.ResponseWriter, r *http.Request, pVars [6]string) (int, error) {
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\",\"p5\"]\n",
+		},
+		{
+			Name:  "statusArrFunc7",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6",
+			Path:  "/p0/p1/p2/p3/p4/p5/p6",
+			Handler: func(w http.ResponseWriter, r *http.Request, pVars [7]string) (int, error) {
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\",\"p5\",\"p6\"]\n",
+		},
+		{
+			Name:  "statusArrFunc8",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6/:var-p7",
+			Path:  "/p0/p1/p2/p3/p4/p5/p6/p7",
+			Handler: func(w http.ResponseWriter, r *http.Request, pVars [8]string) (int, error) {
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\",\"p5\",\"p6\",\"p7\"]\n",
+		},
+		{
+			Name:  "statusCtxFunc0",
+			Route: "/",
+			Path:  "/",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode([]string{})
+				return 0, nil
+			},
+			Expected: "[]\n",
+		},
+		{
+			Name:  "statusCtxFunc1",
+			Route: "/:var-p0",
+			Path:  "/p0",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0 string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+				})
+				return 0, nil
+			},
+			Expected: "[\"p0\"]\n",
+		},
+		{
+			Name:  "statusCtxArrFunc1",
+			Route: "/:var-p0",
+			Path:  "/p0",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [1]string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\"]\n",
+		},
+		{
+			Name:  "statusCtxFunc2",
+			Route: "/:var-p0/:var-p1",
+			Path:  "/p0/p1",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1 string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+					p1,
+				})
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\"]\n",
+		},
+		{
+			Name:  "statusCtxArrFunc2",
+			Route: "/:var-p0/:var-p1",
+			Path:  "/p0/p1",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [2]string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\"]\n",
+		},
+		{
+			Name:  "statusCtxFunc3",
+			Route: "/:var-p0/:var-p1/:var-p2",
+			Path:  "/p0/p1/p2",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2 string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+					p1,
+					p2,
+				})
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\"]\n",
+		},
+		{
+			Name:  "statusCtxArrFunc3",
+			Route: "/:var-p0/:var-p1/:var-p2",
+			Path:  "/p0/p1/p2",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [3]string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\"]\n",
+		},
+		{
+			Name:  "statusCtxFunc4",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3",
+			Path:  "/p0/p1/p2/p3",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode([]string{
+					p0,
+					p1,
+					p2,
+					p3,
+				})
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\"]\n",
+		},
+		{
+			Name:  "statusCtxArrFunc4",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3",
+			Path:  "/p0/p1/p2/p3",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [4]string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\"]\n",
+		},
+		{
+			Name:  "statusCtxArrFunc5",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4",
+			Path:  "/p0/p1/p2/p3/p4",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [5]string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\"]\n",
+		},
+		{
+			Name:  "statusCtxArrFunc6",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5",
+			Path:  "/p0/p1/p2/p3/p4/p5",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [6]string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\",\"p5\"]\n",
+		},
+		{
+			Name:  "statusCtxArrFunc7",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6",
+			Path:  "/p0/p1/p2/p3/p4/p5/p6",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [7]string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\",\"p5\",\"p6\"]\n",
+		},
+		{
+			Name:  "statusCtxArrFunc8",
+			Route: "/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6/:var-p7",
+			Path:  "/p0/p1/p2/p3/p4/p5/p6/p7",
+			Handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [8]string) (int, error) {
+				checkCtx(ctx, r)
+				_ = json.NewEncoder(w).Encode(pVars)
+				return 0, nil
+			},
+			Expected: "[\"p0\",\"p1\",\"p2\",\"p3\",\"p4\",\"p5\",\"p6\",\"p7\"]\n",
+		},
 	} {
 		t.Run(c.Name, func(t *testing.T) {
 			tbl, err := rte.New([]rte.Route{
@@ -193,6 +639,13 @@ func TestFuncs(t *testing.T) {
 	}
 }
 
+// checkCtx panics if the handler wasn't provided the request's context
+func checkCtx(ctx context.Context, r *http.Request) {
+	if ctx != r.Context() {
+		panic("handler wasn't passed the request context")
+	}
+}
+
 func BenchmarkFuncs(b *testing.B) {
 	for _, c := range []struct {
 		Name    string
@@ -291,6 +744,305 @@ func BenchmarkFuncs(b *testing.B) {
 			func(w http.ResponseWriter, r *http.Request, pVars [8]string) {
 			},
 		},
+		{
+			"ctxFunc0",
+			"/",
+			"/",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			},
+		},
+		{
+			"ctxFunc1",
+			"/:var-p0",
+			"/p0",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0 string) {
+			},
+		},
+		{
+			"ctxArrFunc1",
+			"/:var-p0",
+			"/p0",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [1]string) {
+			},
+		},
+		{
+			"ctxFunc2",
+			"/:var-p0/:var-p1",
+			"/p0/p1",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1 string) {
+			},
+		},
+		{
+			"ctxArrFunc2",
+			"/:var-p0/:var-p1",
+			"/p0/p1",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [2]string) {
+			},
+		},
+		{
+			"ctxFunc3",
+			"/:var-p0/:var-p1/:var-p2",
+			"/p0/p1/p2",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2 string) {
+			},
+		},
+		{
+			"ctxArrFunc3",
+			"/:var-p0/:var-p1/:var-p2",
+			"/p0/p1/p2",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [3]string) {
+			},
+		},
+		{
+			"ctxFunc4",
+			"/:var-p0/:var-p1/:var-p2/:var-p3",
+			"/p0/p1/p2/p3",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string) {
+			},
+		},
+		{
+			"ctxArrFunc4",
+			"/:var-p0/:var-p1/:var-p2/:var-p3",
+			"/p0/p1/p2/p3",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [4]string) {
+			},
+		},
+		{
+			"ctxArrFunc5",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4",
+			"/p0/p1/p2/p3/p4",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [5]string) {
+			},
+		},
+		{
+			"ctxArrFunc6",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5",
+			"/p0/p1/p2/p3/p4/p5",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [6]string) {
+			},
+		},
+		{
+			"ctxArrFunc7",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6",
+			"/p0/p1/p2/p3/p4/p5/p6",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [7]string) {
+			},
+		},
+		{
+			"ctxArrFunc8",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6/:var-p7",
+			"/p0/p1/p2/p3/p4/p5/p6/p7",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [8]string) {
+			},
+		},
+		{
+			"statusFunc0",
+			"/",
+			"/",
+			func(w http.ResponseWriter, r *http.Request) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusFunc1",
+			"/:var-p0",
+			"/p0",
+			func(w http.ResponseWriter, r *http.Request, p0 string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusArrFunc1",
+			"/:var-p0",
+			"/p0",
+			func(w http.ResponseWriter, r *http.Request, pVars [1]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusFunc2",
+			"/:var-p0/:var-p1",
+			"/p0/p1",
+			func(w http.ResponseWriter, r *http.Request, p0, p1 string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusArrFunc2",
+			"/:var-p0/:var-p1",
+			"/p0/p1",
+			func(w http.ResponseWriter, r *http.Request, pVars [2]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusFunc3",
+			"/:var-p0/:var-p1/:var-p2",
+			"/p0/p1/p2",
+			func(w http.ResponseWriter, r *http.Request, p0, p1, p2 string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusArrFunc3",
+			"/:var-p0/:var-p1/:var-p2",
+			"/p0/p1/p2",
+			func(w http.ResponseWriter, r *http.Request, pVars [3]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusFunc4",
+			"/:var-p0/:var-p1/:var-p2/:var-p3",
+			"/p0/p1/p2/p3",
+			func(w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusArrFunc4",
+			"/:var-p0/:var-p1/:var-p2/:var-p3",
+			"/p0/p1/p2/p3",
+			func(w http.ResponseWriter, r *http.Request, pVars [4]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusArrFunc5",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4",
+			"/p0/p1/p2/p3/p4",
+			func(w http.ResponseWriter, r *http.Request, pVars [5]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusArrFunc6",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5",
+			"/p0/p1/p2/p3/p4/p5",
+			func(w http.ResponseWriter, r *http.Request, pVars [6]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusArrFunc7",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6",
+			"/p0/p1/p2/p3/p4/p5/p6",
+			func(w http.ResponseWriter, r *http.Request, pVars [7]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusArrFunc8",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6/:var-p7",
+			"/p0/p1/p2/p3/p4/p5/p6/p7",
+			func(w http.ResponseWriter, r *http.Request, pVars [8]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxFunc0",
+			"/",
+			"/",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxFunc1",
+			"/:var-p0",
+			"/p0",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0 string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxArrFunc1",
+			"/:var-p0",
+			"/p0",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [1]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxFunc2",
+			"/:var-p0/:var-p1",
+			"/p0/p1",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1 string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxArrFunc2",
+			"/:var-p0/:var-p1",
+			"/p0/p1",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [2]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxFunc3",
+			"/:var-p0/:var-p1/:var-p2",
+			"/p0/p1/p2",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2 string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxArrFunc3",
+			"/:var-p0/:var-p1/:var-p2",
+			"/p0/p1/p2",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [3]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxFunc4",
+			"/:var-p0/:var-p1/:var-p2/:var-p3",
+			"/p0/p1/p2/p3",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, p0, p1, p2, p3 string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxArrFunc4",
+			"/:var-p0/:var-p1/:var-p2/:var-p3",
+			"/p0/p1/p2/p3",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [4]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxArrFunc5",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4",
+			"/p0/p1/p2/p3/p4",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [5]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxArrFunc6",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5",
+			"/p0/p1/p2/p3/p4/p5",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [6]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxArrFunc7",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6",
+			"/p0/p1/p2/p3/p4/p5/p6",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [7]string) (int, error) {
+				return 0, nil
+			},
+		},
+		{
+			"statusCtxArrFunc8",
+			"/:var-p0/:var-p1/:var-p2/:var-p3/:var-p4/:var-p5/:var-p6/:var-p7",
+			"/p0/p1/p2/p3/p4/p5/p6/p7",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, pVars [8]string) (int, error) {
+				return 0, nil
+			},
+		},
 	} {
 		b.Run(c.Name, func(b *testing.B) {
 			tbl := rte.Must([]rte.Route{
@@ -0,0 +1,15 @@
+package funcs
+
+import "net/http"
+
+// writeStatus completes the response of a handler which returned a status and an error: a non-nil error is passed
+// to onErr along with the status, and otherwise a non-zero status is written. A zero status with a nil error writes
+// nothing, leaving the response to the handler.
+func writeStatus(w http.ResponseWriter, r *http.Request, status int, err error, onErr ErrorHandler) {
+	switch {
+	case err != nil:
+		onErr(w, r, status, err)
+	case status != 0:
+		w.WriteHeader(status)
+	}
+}
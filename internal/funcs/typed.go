@@ -0,0 +1,129 @@
+package funcs
+
+import (
+	"errors"
+	"net/http"
+	"net/netip"
+	"strconv"
+)
+
+// ErrNegative is returned when a negative value is provided for an unsigned path variable
+var ErrNegative = errors.New("value cannot be negative")
+
+// ParseErrorHandler responds to a request whose path variable at idx couldn't be parsed as kind
+type ParseErrorHandler func(w http.ResponseWriter, r *http.Request, idx int, value, kind string, err error)
+
+// ConvertTyped converts the provided interface to a Handler if it's one of the supported typed forms, whose path
+// variables are parsed before the handler is invoked; if parsing fails, onErr is invoked instead.
+func ConvertTyped(i interface{}, onErr ParseErrorHandler) (Handler, int, bool) {
+	h, kinds := convertTyped(i, onErr)
+	return h, len(kinds), kinds != nil
+}
+
+// Kind describes how a typed handler parses one of its path variables
+type Kind struct {
+	// Name is the type the variable is parsed as, e.g. "int64"
+	Name string
+	// Check returns the error parsing the value would produce, if any
+	Check func(value string) error
+}
+
+var (
+	kindInt64 = Kind{"int64", func(s string) error {
+		_, err := parseInt(s)
+		return err
+	}}
+	kindUint64 = Kind{"uint64", func(s string) error {
+		_, err := parseUint(s)
+		return err
+	}}
+	kindAddr = Kind{"netip.Addr", func(s string) error {
+		_, err := netip.ParseAddr(s)
+		return err
+	}}
+)
+
+// TypedKinds returns how each path variable is parsed if the provided interface is one of the typed forms supported
+// by ConvertTyped; otherwise, it returns nil.
+func TypedKinds(i interface{}) []Kind {
+	_, kinds := convertTyped(i, nil)
+	return kinds
+}
+
+// convertTyped lists the typed forms for both ConvertTyped and TypedKinds: it returns the Handler for the provided
+// interface and how each of its path variables is parsed, or nil and nil if it isn't one of them
+func convertTyped(i interface{}, onErr ParseErrorHandler) (Handler, []Kind) {
+	switch v := i.(type) {
+	case func(w http.ResponseWriter, r *http.Request, p0 int64):
+		return funcI1(v, onErr), []Kind{kindInt64}
+	case func(w http.ResponseWriter, r *http.Request, p0 uint64):
+		return funcU1(v, onErr), []Kind{kindUint64}
+	case func(w http.ResponseWriter, r *http.Request, p0 netip.Addr):
+		return funcA1(v, onErr), []Kind{kindAddr}
+	default:
+		return nil, nil
+	}
+}
+
+// funcI1 takes in a handler expecting one int64 path variable and returns a Handler which parses it
+func funcI1(f func(w http.ResponseWriter, r *http.Request, p0 int64), onErr ParseErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		p0, err := parseInt(pVars[0])
+		if err != nil {
+			onErr(w, r, 0, pVars[0], kindInt64.Name, err)
+			return
+		}
+		f(w, r, p0)
+	}
+}
+
+// funcU1 takes in a handler expecting one uint64 path variable and returns a Handler which parses it
+func funcU1(f func(w http.ResponseWriter, r *http.Request, p0 uint64), onErr ParseErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		p0, err := parseUint(pVars[0])
+		if err != nil {
+			onErr(w, r, 0, pVars[0], kindUint64.Name, err)
+			return
+		}
+		f(w, r, p0)
+	}
+}
+
+// funcA1 takes in a handler expecting one IP address path variable and returns a Handler which parses it
+func funcA1(f func(w http.ResponseWriter, r *http.Request, p0 netip.Addr), onErr ParseErrorHandler) Handler {
+	return func(w http.ResponseWriter, r *http.Request, pVars PathVars) {
+		p0, err := netip.ParseAddr(pVars[0])
+		if err != nil {
+			onErr(w, r, 0, pVars[0], kindAddr.Name, err)
+			return
+		}
+		f(w, r, p0)
+	}
+}
+
+// parseInt parses a base 10 int64
+func parseInt(s string) (int64, error) {
+	v, err := strconv.ParseInt(s, 10, 64)
+	return v, unwrapNumError(err)
+}
+
+// parseUint parses a base 10 uint64, clearly distinguishing negative values, which strconv reports as a syntax error;
+// "-0" isn't negative, so it's left to strconv
+func parseUint(s string) (uint64, error) {
+	if len(s) > 1 && s[0] == '-' {
+		if v, err := strconv.ParseUint(s[1:], 10, 64); (err == nil && v != 0) || errors.Is(err, strconv.ErrRange) {
+			return 0, ErrNegative
+		}
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	return v, unwrapNumError(err)
+}
+
+// unwrapNumError drops strconv's redundant context -- the function and value -- which the caller reports anyway
+func unwrapNumError(err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return numErr.Err
+	}
+	return err
+}
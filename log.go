@@ -0,0 +1,68 @@
+package rte
+
+import (
+	"context"
+	"net/http"
+)
+
+// FieldLogger is a minimal structured logger; adapt whichever logging backend you prefer to it.
+type FieldLogger interface {
+	// With returns a logger which includes the provided field with everything it logs
+	With(key string, value interface{}) FieldLogger
+	// Log emits a message
+	Log(msg string)
+}
+
+type contextKey int
+
+const (
+	ctxKeyLogger contextKey = iota
+	ctxKeyMatch
+	ctxKeyQuery
+)
+
+// InjectLogger registers a middleware across all provided routes which stores a request scoped logger in the request
+// context, where it can be retrieved with rte.Logger. The logger is derived from the provided one with the field
+// "route" set to the route's pattern (e.g. "GET /foo/:foo_id") and, if the request has an X-Request-Id header, the
+// field "request_id" set to its value. The middleware is applied outside of any existing middleware, so that all of
+// a route's middleware can make use of the logger.
+func InjectLogger(l FieldLogger, routes []Route) []Route {
+	return wrapEach(func(route Route) Middleware {
+		routeL := l.With("route", route.String())
+		return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			reqL := routeL
+			if id := r.Header.Get("X-Request-Id"); id != "" {
+				reqL = reqL.With("request_id", id)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyLogger, reqL)))
+		})
+	}, routes)
+}
+
+// Logger returns the request scoped logger stored by InjectLogger. If there is none, a logger which discards
+// everything is returned, so the result is always safe to use.
+func Logger(ctx context.Context) FieldLogger {
+	if l, ok := ctx.Value(ctxKeyLogger).(FieldLogger); ok {
+		return l
+	}
+	return nopLogger{}
+}
+
+// LoggingNotFound returns a handler, suitable for a Table's Default, which logs the method and path (without the query)
+// of each request with the fields "method" and "path" before deferring to next -- e.g. http.NotFoundHandler() -- so
+// that misconfigured clients are visible. As misses are routine, the logger should usually be one emitting at a
+// debug level.
+func LoggingNotFound(l FieldLogger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.With("method", r.Method).With("path", r.URL.EscapedPath()).Log("no route matched")
+		next.ServeHTTP(w, r)
+	})
+}
+
+type nopLogger struct{}
+
+func (n nopLogger) With(string, interface{}) FieldLogger {
+	return n
+}
+
+func (nopLogger) Log(string) {}
@@ -0,0 +1,95 @@
+package rte_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jwilner/rte"
+)
+
+// fieldLogger records the fields it was built with and everything it logged
+type fieldLogger struct {
+	fields []string
+	logged *[]string
+}
+
+func newFieldLogger() fieldLogger {
+	return fieldLogger{logged: new([]string)}
+}
+
+func (l fieldLogger) With(key string, value interface{}) rte.FieldLogger {
+	return fieldLogger{
+		fields: append(append([]string(nil), l.fields...), fmt.Sprintf("%v=%v", key, value)),
+		logged: l.logged,
+	}
+}
+
+func (l fieldLogger) Log(msg string) {
+	*l.logged = append(*l.logged, strings.Join(append(append([]string(nil), l.fields...), msg), " "))
+}
+
+func TestInjectLogger(t *testing.T) {
+	l := newFieldLogger()
+	tbl := rte.Must(rte.InjectLogger(l, rte.Routes(
+		"GET /foo/:foo_id", func(w http.ResponseWriter, r *http.Request, fooID string) {
+			rte.Logger(r.Context()).Log("handling " + fooID)
+		},
+		"POST /bar", func(w http.ResponseWriter, r *http.Request) {
+			rte.Logger(r.Context()).Log("handling bar")
+		}, rte.MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			rte.Logger(r.Context()).Log("in middleware")
+			next.ServeHTTP(w, r)
+		}),
+	)))
+
+	tbl.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo/abc", nil))
+
+	req := httptest.NewRequest("POST", "/bar", nil)
+	req.Header.Set("X-Request-Id", "req-123")
+	tbl.ServeHTTP(httptest.NewRecorder(), req)
+
+	want := []string{
+		"route=GET /foo/:foo_id handling abc",
+		"route=POST /bar request_id=req-123 in middleware",
+		"route=POST /bar request_id=req-123 handling bar",
+	}
+	if !reflect.DeepEqual(*l.logged, want) {
+		t.Fatalf("want %q but got %q", want, *l.logged)
+	}
+}
+
+func TestLoggerMissing(t *testing.T) {
+	l := rte.Logger(context.Background())
+	if l == nil {
+		t.Fatal("expected a non-nil logger")
+	}
+	l.With("foo", "bar").Log("discarded")
+}
+
+func TestLoggingNotFound(t *testing.T) {
+	l := newFieldLogger()
+	tbl := rte.Must(rte.Routes(
+		"GET /foo", func(w http.ResponseWriter, r *http.Request) {},
+	))
+	tbl.Default = rte.LoggingNotFound(l, http.NotFoundHandler())
+
+	w := httptest.NewRecorder()
+	tbl.ServeHTTP(w, httptest.NewRequest("GET", "/foo", nil))
+	if w.Code != 200 || len(*l.logged) != 0 {
+		t.Fatalf("want a quiet 200 but got %v %q", w.Code, *l.logged)
+	}
+
+	w = httptest.NewRecorder()
+	tbl.ServeHTTP(w, httptest.NewRequest("POST", "/bar/baz?token=secret", nil))
+	if w.Code != 404 {
+		t.Fatalf("want 404 but got %v", w.Code)
+	}
+	if want := []string{"method=POST path=/bar/baz no route matched"}; !reflect.DeepEqual(*l.logged, want) {
+		t.Fatalf("want logged %q but got %q", want, *l.logged)
+	}
+}
@@ -0,0 +1,51 @@
+package rte
+
+import (
+	"net/http"
+	"time"
+)
+
+// Observer records observations, e.g. a Prometheus histogram
+type Observer interface {
+	Observe(float64)
+}
+
+// HistogramFactory returns the histogram into which the latencies of the provided route should be recorded; route is
+// the route's pattern (e.g. "GET /foo/:foo_id") and buckets are the upper bounds, in seconds, of the histogram's
+// buckets. It's the point at which a metrics backend is plugged in -- e.g. for Prometheus:
+//
+//	func(route string, buckets []float64) rte.Observer {
+//		h := prometheus.NewHistogram(prometheus.HistogramOpts{
+//			Name:        "http_request_duration_seconds",
+//			ConstLabels: prometheus.Labels{"route": route},
+//			Buckets:     buckets,
+//		})
+//		prometheus.MustRegister(h)
+//		return h
+//	}
+type HistogramFactory func(route string, buckets []float64) Observer
+
+// DefaultLatencyBuckets are the histogram buckets used for routes without specific configuration; they match
+// Prometheus' defaults.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// InstrumentLatency registers a middleware across all provided routes which records the duration of each request, in
+// seconds, to a histogram created for the route by the factory. buckets configures the histogram buckets of specific
+// routes, keyed by route pattern (e.g. "GET /foo/:foo_id"); any other routes use DefaultLatencyBuckets. A route's
+// latency covers its handler and the middleware it already has, e.g. authentication, but not middleware registered
+// afterwards.
+func InstrumentLatency(f HistogramFactory, buckets map[string][]float64, routes []Route) []Route {
+	return wrapEach(func(route Route) Middleware {
+		pattern := route.String()
+		b, ok := buckets[pattern]
+		if !ok {
+			b = DefaultLatencyBuckets
+		}
+		o := f(pattern, b)
+		return MiddlewareFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			start := time.Now()
+			next.ServeHTTP(w, r)
+			o.Observe(time.Since(start).Seconds())
+		})
+	}, routes)
+}
@@ -0,0 +1,59 @@
+package rte_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jwilner/rte"
+)
+
+// histogram counts observations into cumulative buckets
+type histogram struct {
+	buckets []float64
+	counts  []int
+}
+
+func (h *histogram) Observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+}
+
+func TestInstrumentLatency(t *testing.T) {
+	histograms := make(map[string]*histogram)
+	factory := func(route string, buckets []float64) rte.Observer {
+		h := &histogram{buckets: buckets, counts: make([]int, len(buckets))}
+		histograms[route] = h
+		return h
+	}
+
+	h := func(http.ResponseWriter, *http.Request) {}
+	tbl := rte.Must(rte.InstrumentLatency(factory, map[string][]float64{
+		"GET /fast": {.0001, 1000},
+	}, rte.Routes(
+		"GET /fast", h,
+		"GET /slow", h,
+	)))
+
+	tbl.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fast", nil))
+	tbl.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+	tbl.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+
+	fast, slow := histograms["GET /fast"], histograms["GET /slow"]
+	if want := []float64{.0001, 1000}; !reflect.DeepEqual(fast.buckets, want) {
+		t.Fatalf("want fast buckets %v but got %v", want, fast.buckets)
+	}
+	if fast.counts[len(fast.counts)-1] != 1 {
+		t.Fatalf("want 1 fast observation but got %v", fast.counts)
+	}
+	if !reflect.DeepEqual(slow.buckets, rte.DefaultLatencyBuckets) {
+		t.Fatalf("want slow buckets %v but got %v", rte.DefaultLatencyBuckets, slow.buckets)
+	}
+	if slow.counts[len(slow.counts)-1] != 2 {
+		t.Fatalf("want 2 slow observations but got %v", slow.counts)
+	}
+}
@@ -0,0 +1,176 @@
+package rte
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// OpenAPIOperation is optional metadata describing a route in the document generated by Table.OpenAPI. Every field
+// may be left empty.
+type OpenAPIOperation struct {
+	// Summary is a short description of the operation
+	Summary string
+	// Description is a longer description of the operation
+	Description string
+	// Params describes the path variables, keyed by their names as they appear in the route's path
+	Params map[string]string
+	// RequestSchema and ResponseSchema are references to the schemas of JSON request and response bodies, e.g.
+	// "#/components/schemas/Post"
+	RequestSchema, ResponseSchema string
+}
+
+// openAPIMethods are the methods which can be described by an OpenAPI path item
+var openAPIMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodPut:     true,
+	http.MethodPost:    true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+	http.MethodHead:    true,
+	http.MethodPatch:   true,
+	http.MethodTrace:   true,
+}
+
+type openAPIDoc struct {
+	OpenAPI string                                 `json:"openapi"`
+	Info    openAPIInfo                            `json:"info"`
+	Paths   map[string]map[string]openAPIOperation `json:"paths"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIBody               `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string            `json:"name"`
+	In          string            `json:"in"`
+	Description string            `json:"description,omitempty"`
+	Required    bool              `json:"required"`
+	Schema      map[string]string `json:"schema"`
+}
+
+type openAPIBody struct {
+	Content map[string]openAPIMedia `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                  `json:"description"`
+	Content     map[string]openAPIMedia `json:"content,omitempty"`
+}
+
+type openAPIMedia struct {
+	Schema map[string]string `json:"schema"`
+}
+
+// OpenAPI generates a minimal OpenAPI 3 document (as JSON) describing the table's routes: each path, with its
+// variables as path parameters, and the methods routed for it, enriched by any Route.OpenAPI metadata. Named routes
+// use their names as operation IDs. Routes for MethodAny or methods OpenAPI doesn't support are omitted, as is
+// everything the table doesn't know -- e.g. query parameters or error responses; the title and version are
+// placeholders to be replaced.
+func (t *Table) OpenAPI() []byte {
+	found := t.handlers()
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].handler.Idx < found[j].handler.Idx
+	})
+
+	doc := openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: "API", Version: "0.0.0"},
+		Paths:   make(map[string]map[string]openAPIOperation),
+	}
+	for _, f := range found {
+		r := f.handler.Route
+		if !openAPIMethods[r.Method] {
+			continue
+		}
+
+		meta := r.OpenAPI
+		if meta == nil {
+			meta = &OpenAPIOperation{}
+		}
+
+		names := openAPINames(f.handler.varNames)
+		path := t.openAPIPath(r.Path, names)
+		op := openAPIOperation{
+			OperationID: r.Name,
+			Summary:     meta.Summary,
+			Description: meta.Description,
+			Responses:   map[string]openAPIResponse{"200": {Description: "OK"}},
+		}
+		for _, name := range names {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name:        name,
+				In:          "path",
+				Description: meta.Params[name],
+				Required:    true,
+				Schema:      map[string]string{"type": "string"},
+			})
+		}
+		if meta.RequestSchema != "" {
+			op.RequestBody = &openAPIBody{Content: openAPIJSON(meta.RequestSchema)}
+		}
+		if meta.ResponseSchema != "" {
+			op.Responses["200"] = openAPIResponse{Description: "OK", Content: openAPIJSON(meta.ResponseSchema)}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]openAPIOperation)
+		}
+		doc.Paths[path][strings.ToLower(r.Method)] = op
+	}
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		// the document consists only of strings, maps, and slices
+		panic("rte.OpenAPI: failed marshaling document: " + err.Error())
+	}
+	return b
+}
+
+// openAPINames names the variables of a route for OpenAPI, which requires every parameter to have a name: unnamed
+// variables are named by their positions, e.g. "param1"
+func openAPINames(varNames []string) []string {
+	names := append([]string{}, varNames...)
+	for i, name := range names {
+		if name == "" {
+			names[i] = uniqueName(fmt.Sprintf("param%d", i+1), names)
+		}
+	}
+	return names
+}
+
+// openAPIPath converts a route path to OpenAPI's templated form with the provided variable names, e.g.
+// "/users/:user_id{6}" to "/users/{user_id}"
+func (t *Table) openAPIPath(path string, names []string) string {
+	var b strings.Builder
+	for i, v := 0, 0; i < len(path); i++ {
+		if path[i] != ':' {
+			b.WriteByte(path[i])
+			continue
+		}
+		j := i + 1
+		for j < len(path) && path[j] != t.sep && !strings.HasPrefix(path[j:], ".:") {
+			j++
+		}
+		b.WriteString("{" + names[v] + "}")
+		i, v = j-1, v+1
+	}
+	return b.String()
+}
+
+func openAPIJSON(ref string) map[string]openAPIMedia {
+	return map[string]openAPIMedia{"application/json": {Schema: map[string]string{"$ref": ref}}}
+}
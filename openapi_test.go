@@ -0,0 +1,118 @@
+package rte_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jwilner/rte"
+)
+
+func TestOpenAPI(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	tbl := rte.Must([]rte.Route{
+		{Method: "GET", Path: "/health", Handler: h},
+		{
+			Name:    "GetPost",
+			Method:  "GET",
+			Path:    "/users/:user_id/posts/:post_id",
+			Handler: h,
+			OpenAPI: &rte.OpenAPIOperation{
+				Summary:        "Get a post",
+				Params:         map[string]string{"post_id": "the post's ID"},
+				ResponseSchema: "#/components/schemas/Post",
+			},
+		},
+		{
+			Method:  "PUT",
+			Path:    "/users/:user_id/posts/:post_id",
+			Handler: h,
+			OpenAPI: &rte.OpenAPIOperation{RequestSchema: "#/components/schemas/Post"},
+		},
+		{Method: "GET", Path: "/articles/:id.:format", Handler: h},
+		{Method: "GET", Path: "/files/:/:name", Handler: h},
+		{Method: "GET", Path: "/blobs/:/:", Handler: h},
+		{Method: rte.MethodAny, Path: "/health", Handler: h},
+		{Method: "BREW", Path: "/coffee", Handler: h},
+	})
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Summary     string `json:"summary"`
+			Parameters  []struct {
+				Name        string `json:"name"`
+				In          string `json:"in"`
+				Description string `json:"description"`
+				Required    bool   `json:"required"`
+			} `json:"parameters"`
+			RequestBody json.RawMessage            `json:"requestBody"`
+			Responses   map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(tbl.OpenAPI(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.OpenAPI != "3.0.3" {
+		t.Fatalf("unexpected version %q", doc.OpenAPI)
+	}
+
+	methods := make(map[string][]string)
+	for path, ops := range doc.Paths {
+		for m := range ops {
+			methods[path] = append(methods[path], m)
+		}
+	}
+	for _, ms := range methods {
+		sort.Strings(ms)
+	}
+	if want := map[string][]string{
+		"/health":                          {"get"},
+		"/users/{user_id}/posts/{post_id}": {"get", "put"},
+		"/articles/{id}.{format}":          {"get"},
+		"/files/{param1}/{name}":           {"get"},
+		"/blobs/{param1}/{param2}":         {"get"},
+	}; !reflect.DeepEqual(methods, want) {
+		t.Fatalf("want paths %v but got %v", want, methods)
+	}
+
+	get := doc.Paths["/users/{user_id}/posts/{post_id}"]["get"]
+	if get.OperationID != "GetPost" || get.Summary != "Get a post" {
+		t.Fatalf("unexpected operation %+v", get)
+	}
+	var params []string
+	for _, p := range get.Parameters {
+		if p.In != "path" || !p.Required {
+			t.Fatalf("unexpected parameter %+v", p)
+		}
+		params = append(params, p.Name+":"+p.Description)
+	}
+	if want := []string{"user_id:", "post_id:the post's ID"}; !reflect.DeepEqual(params, want) {
+		t.Fatalf("want params %v but got %v", want, params)
+	}
+	if _, ok := get.Responses["200"]; !ok || get.RequestBody != nil {
+		t.Fatalf("unexpected bodies %+v", get)
+	}
+
+	if put := doc.Paths["/users/{user_id}/posts/{post_id}"]["put"]; put.RequestBody == nil {
+		t.Fatalf("expected a request body: %+v", put)
+	}
+
+	// unnamed variables are named by their positions
+	for path, want := range map[string][]string{
+		"/files/{param1}/{name}":   {"param1", "name"},
+		"/blobs/{param1}/{param2}": {"param1", "param2"},
+	} {
+		var names []string
+		for _, p := range doc.Paths[path]["get"].Parameters {
+			names = append(names, p.Name)
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Fatalf("%v: want params %v but got %v", path, want, names)
+		}
+	}
+}
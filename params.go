@@ -0,0 +1,133 @@
+package rte
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/jwilner/rte/internal/funcs"
+)
+
+// ErrNoRoute is returned by ValidatePath when no route matches
+var ErrNoRoute = errors.New("no route matches")
+
+// ErrNegative is the cause of a ParseError when a negative value is provided for an unsigned path variable
+var ErrNegative = funcs.ErrNegative
+
+// ParseError describes a path variable which couldn't be parsed as the type a handler requires -- e.g. "-5" for a
+// handler taking a uint64.
+type ParseError struct {
+	// Index is the position of the variable within the path
+	Index int
+	// Value is the variable's value as provided in the request
+	Value string
+	// Kind is the type the value was parsed as, e.g. "uint64"
+	Kind string
+	// Err is the cause, e.g. ErrNegative or strconv.ErrSyntax
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("path variable %d: invalid %v %q: %v", e.Index, e.Kind, e.Value, e.Err)
+}
+
+// Unwrap returns the cause
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ValidatePath checks that a request with the provided method and path (as it would be sent, i.e. escaped) would be
+// routed to a handler without failing to parse its path variables -- e.g. for checking generated links before they're
+// published. It returns ErrNoRoute if no route matches, and a *ParseError if a typed handler (e.g. one taking an
+// int64) wouldn't accept a variable. Constraints enforced by the handlers themselves, e.g. Enum, aren't checked.
+func (t *Table) ValidatePath(method, path string) error {
+	var variables funcs.PathVars
+	n, mh := t.matchVars(method, t.cleanPath(path), variables[:])
+	if mh == nil {
+		return ErrNoRoute
+	}
+
+	for i, v := range variables[:n] {
+		if t.decodesVars() {
+			unescaped, err := t.unescape(v)
+			if err != nil {
+				return &ParseError{Index: i, Value: v, Kind: "percent-encoded string", Err: err}
+			}
+			v = unescaped
+		}
+		if i < len(mh.kinds) {
+			if err := mh.kinds[i].Check(v); err != nil {
+				return &ParseError{Index: i, Value: v, Kind: mh.kinds[i].Name, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// parseErrorHandler returns the function invoked when one of the route's path variables can't be parsed, which
+// prefers the route's OnParseError to the table's
+func (t *Table) parseErrorHandler(route Route) funcs.ParseErrorHandler {
+	if route.OnParseError == nil {
+		return t.parseError
+	}
+	return func(w http.ResponseWriter, r *http.Request, idx int, value, kind string, err error) {
+		route.OnParseError(w, r, &ParseError{Index: idx, Value: value, Kind: kind, Err: err})
+	}
+}
+
+// parseError responds to a path variable which can't be parsed, deferring to the table's OnParseError as of request
+// time
+func (t *Table) parseError(w http.ResponseWriter, r *http.Request, idx int, value, kind string, err error) {
+	pe := &ParseError{Index: idx, Value: value, Kind: kind, Err: err}
+	if t.OnParseError != nil {
+		t.OnParseError(w, r, pe)
+		return
+	}
+	http.Error(w, pe.Error(), http.StatusBadRequest)
+}
+
+// decodesVars reports whether path variables are decoded before they're provided to handlers
+func (t *Table) decodesVars() bool {
+	return t.UnescapeVars || t.DecodePlusAsSpace
+}
+
+// unescape decodes a path variable per UnescapeVars or DecodePlusAsSpace
+func (t *Table) unescape(v string) (string, error) {
+	if t.DecodePlusAsSpace {
+		return url.QueryUnescape(v)
+	}
+	return url.PathUnescape(v)
+}
+
+// unescapeVars percent-decodes the variables in place, responding with onErr if any is malformed
+func (t *Table) unescapeVars(w http.ResponseWriter, r *http.Request, onErr funcs.ParseErrorHandler, vars []string) bool {
+	for i, v := range vars {
+		unescaped, err := t.unescape(v)
+		if err != nil {
+			onErr(w, r, i, v, "percent-encoded string", err)
+			return false
+		}
+		vars[i] = unescaped
+	}
+	return true
+}
+
+// convertBuiltin converts the handler if it's one of the forms rte supports natively; handlers returned by Enum defer
+// to onMiss
+func convertBuiltin(
+	i interface{},
+	onParseErr funcs.ParseErrorHandler,
+	onErr funcs.ErrorHandler,
+	onMiss http.Handler,
+) (funcs.Handler, int, bool) {
+	if e, ok := i.(EnumHandler); ok {
+		return func(w http.ResponseWriter, r *http.Request, pathVars funcs.PathVars) {
+			e(w, r, pathVars[0], onMiss)
+		}, 1, true
+	}
+	if h, n, ok := funcs.Convert(i, onErr); ok {
+		return h, n, true
+	}
+	return funcs.ConvertTyped(i, onParseErr)
+}
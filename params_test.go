@@ -0,0 +1,230 @@
+package rte_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+
+	"github.com/jwilner/rte"
+)
+
+func TestTypedParams(t *testing.T) {
+	tbl := rte.Must(rte.Routes(
+		"GET /items/:count", func(w http.ResponseWriter, r *http.Request, count uint64) {
+			_, _ = fmt.Fprintf(w, "count %d", count)
+		},
+		"GET /offsets/:offset", func(w http.ResponseWriter, r *http.Request, offset int64) {
+			_, _ = fmt.Fprintf(w, "offset %d", offset)
+		},
+		"GET /hosts/:ip/status", func(w http.ResponseWriter, r *http.Request, ip netip.Addr) {
+			_, _ = fmt.Fprintf(w, "host %v (v6: %v)", ip, ip.Is6())
+		},
+	))
+
+	for _, c := range []struct {
+		path, want string
+		code       int
+	}{
+		{"/items/5", "count 5", 200},
+		{"/items/18446744073709551615", "count 18446744073709551615", 200},
+		{"/items/-5", "path variable 0: invalid uint64 \"-5\": value cannot be negative\n", 400},
+		{"/items/-18446744073709551616", "path variable 0: invalid uint64 \"-18446744073709551616\": value cannot be negative\n", 400},
+		{"/items/-abc", "path variable 0: invalid uint64 \"-abc\": invalid syntax\n", 400},
+		{"/items/-0", "path variable 0: invalid uint64 \"-0\": invalid syntax\n", 400},
+		{"/items/18446744073709551616", "path variable 0: invalid uint64 \"18446744073709551616\": value out of range\n", 400},
+		{"/offsets/-5", "offset -5", 200},
+		{"/offsets/five", "path variable 0: invalid int64 \"five\": invalid syntax\n", 400},
+		{"/hosts/10.0.0.1/status", "host 10.0.0.1 (v6: false)", 200},
+		{"/hosts/2001:db8::1/status", "host 2001:db8::1 (v6: true)", 200},
+		{"/hosts/10.0.0.256/status", "path variable 0: invalid netip.Addr \"10.0.0.256\": ParseAddr(\"10.0.0.256\"): IPv4 field has value >255\n", 400},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if w.Code != c.code || w.Body.String() != c.want {
+				t.Fatalf("want %v %q but got %v %q", c.code, c.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOnParseError(t *testing.T) {
+	var got *rte.ParseError
+	tbl := rte.Must(rte.Routes(
+		"GET /items/:count", func(w http.ResponseWriter, r *http.Request, count uint64) {},
+	))
+	tbl.OnParseError = func(w http.ResponseWriter, r *http.Request, err *rte.ParseError) {
+		got = err
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+
+	for _, c := range []struct {
+		path string
+		want error
+	}{
+		{"/items/-5", rte.ErrNegative},
+		{"/items/abc", strconv.ErrSyntax},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("want 422 but got %v", w.Code)
+			}
+			if got == nil || got.Kind != "uint64" || got.Index != 0 || !errors.Is(got, c.want) {
+				t.Fatalf("want a uint64 parse error caused by %v but got %#v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestRouteOnParseError(t *testing.T) {
+	tbl := rte.Must([]rte.Route{
+		{
+			Method:  "GET",
+			Path:    "/items/:count",
+			Handler: func(w http.ResponseWriter, r *http.Request, count uint64) {},
+			OnParseError: func(w http.ResponseWriter, r *http.Request, err *rte.ParseError) {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_, _ = fmt.Fprintf(w, `{"field":%d,"kind":%q}`, err.Index, err.Kind)
+			},
+		},
+		{Method: "GET", Path: "/offsets/:offset", Handler: func(w http.ResponseWriter, r *http.Request, offset int64) {}},
+	})
+	tbl.UnescapeVars = true
+	tbl.OnParseError = func(w http.ResponseWriter, r *http.Request, err *rte.ParseError) {
+		http.Error(w, "table: "+err.Error(), http.StatusBadRequest)
+	}
+
+	for _, c := range []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"/items/-5", 422, `{"field":0,"kind":"uint64"}`},
+		{"/items/%zz", 422, `{"field":0,"kind":"percent-encoded string"}`},
+		{"/offsets/five", 400, "table: path variable 0: invalid int64 \"five\": invalid syntax\n"},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			// constructed directly because httptest.NewRequest rejects malformed encodings
+			tbl.ServeHTTP(w, &http.Request{Method: "GET", RequestURI: c.path})
+			if w.Code != c.wantCode || w.Body.String() != c.wantBody {
+				t.Fatalf("want %v %q but got %v %q", c.wantCode, c.wantBody, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUnescapeVars(t *testing.T) {
+	tbl := rte.Must(rte.Routes(
+		"GET /files/:name", func(w http.ResponseWriter, r *http.Request, name string) {
+			_, _ = fmt.Fprintf(w, "file %q", name)
+		},
+	))
+
+	for _, c := range []struct {
+		name     string
+		unescape bool
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"disabled", false, "/files/a%20b", 200, `file "a%20b"`},
+		{"decoded", true, "/files/a%20b%2Fc", 200, `file "a b/c"`},
+		{"plain", true, "/files/abc", 200, `file "abc"`},
+		{"malformed", true, "/files/%zz", 400, "path variable 0: invalid percent-encoded string \"%zz\": invalid URL escape \"%zz\"\n"},
+		{"malformed disabled", false, "/files/%zz", 200, `file "%zz"`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tbl.UnescapeVars = c.unescape
+
+			w := httptest.NewRecorder()
+			// constructed directly because httptest.NewRequest rejects malformed encodings
+			tbl.ServeHTTP(w, &http.Request{Method: "GET", RequestURI: c.path})
+			if w.Code != c.wantCode || w.Body.String() != c.wantBody {
+				t.Fatalf("want %v %q but got %v %q", c.wantCode, c.wantBody, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDecodePlusAsSpace(t *testing.T) {
+	tbl := rte.Must(rte.Routes(
+		"GET /q/:query", func(w http.ResponseWriter, r *http.Request, query string) {
+			_, _ = fmt.Fprint(w, query)
+		},
+	))
+
+	for _, c := range []struct {
+		name       string
+		plus       bool
+		unescape   bool
+		path, want string
+	}{
+		{"disabled", false, false, "/q/hello+world", "hello+world"},
+		{"unescaped only", false, true, "/q/hello+world", "hello+world"},
+		{"enabled", true, false, "/q/hello+world", "hello world"},
+		{"enabled with unescape", true, true, "/q/hello+world", "hello world"},
+		{"encoded plus", true, false, "/q/1%2B1", "1+1"},
+		{"percent decoded", true, false, "/q/a%20b", "a b"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tbl.DecodePlusAsSpace, tbl.UnescapeVars = c.plus, c.unescape
+
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if w.Code != 200 || w.Body.String() != c.want {
+				t.Fatalf("want 200 %q but got %v %q", c.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	tbl := rte.Must(rte.Routes(
+		"GET /offsets/:offset", func(w http.ResponseWriter, r *http.Request, offset int64) {},
+		"GET /hosts/:ip", func(w http.ResponseWriter, r *http.Request, ip netip.Addr) {},
+		"GET /users/:name", func(w http.ResponseWriter, r *http.Request, name string) {},
+	))
+
+	for _, c := range []struct {
+		method, path string
+		wantErr      error
+		wantKind     string
+	}{
+		{"GET", "/offsets/-5", nil, ""},
+		{"GET", "/offsets/five", strconv.ErrSyntax, "int64"},
+		{"GET", "/offsets/99999999999999999999", strconv.ErrRange, "int64"},
+		{"GET", "/hosts/::1", nil, ""},
+		{"GET", "/hosts/nope", nil, "netip.Addr"},
+		{"GET", "/users/anything", nil, ""},
+		{"POST", "/users/anything", rte.ErrNoRoute, ""},
+		{"GET", "/unknown", rte.ErrNoRoute, ""},
+	} {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			err := tbl.ValidatePath(c.method, c.path)
+
+			var pe *rte.ParseError
+			switch {
+			case c.wantKind != "":
+				if !errors.As(err, &pe) || pe.Kind != c.wantKind || pe.Index != 0 {
+					t.Fatalf("want a %v parse error but got %v", c.wantKind, err)
+				}
+				if c.wantErr != nil && !errors.Is(err, c.wantErr) {
+					t.Fatalf("want %v but got %v", c.wantErr, err)
+				}
+			case c.wantErr != nil:
+				if !errors.Is(err, c.wantErr) {
+					t.Fatalf("want %v but got %v", c.wantErr, err)
+				}
+			case err != nil:
+				t.Fatalf("want no error but got %v", err)
+			}
+		})
+	}
+}
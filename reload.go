@@ -0,0 +1,49 @@
+package rte
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// ReloadableTable serves requests with a Table which can be replaced at any time. Reload builds the new table fully
+// before swapping it in atomically, so requests are always served by a complete table: in-flight requests finish on
+// the table they started with and subsequent requests use the new one. It's the safe alternative to mutating a live
+// table.
+type ReloadableTable struct {
+	opts    []Option
+	current atomic.Pointer[Table]
+}
+
+// NewReloadable builds routes into a ReloadableTable or returns an error. The options are applied to every table
+// built, including on reload; to configure table fields such as Default, use an option so that it's preserved:
+//
+//	rte.NewReloadable(routes, func(t *rte.Table) {
+//		t.Default = notFound
+//	})
+func NewReloadable(routes []Route, opts ...Option) (*ReloadableTable, error) {
+	rt := &ReloadableTable{opts: opts}
+	if err := rt.Reload(routes); err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+// Reload builds routes into a new Table and, if successful, replaces the current table with it. If there's an error,
+// the current table is left in place.
+func (rt *ReloadableTable) Reload(routes []Route) error {
+	t, err := New(routes, rt.opts...)
+	if err != nil {
+		return err
+	}
+	rt.current.Store(t)
+	return nil
+}
+
+// Table returns the current table
+func (rt *ReloadableTable) Table() *Table {
+	return rt.current.Load()
+}
+
+func (rt *ReloadableTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.current.Load().ServeHTTP(w, r)
+}
@@ -0,0 +1,95 @@
+package rte_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jwilner/rte"
+)
+
+func TestReloadableTable(t *testing.T) {
+	const numRoutes = 50
+
+	version := func(v int) []rte.Route {
+		var routes []rte.Route
+		for i := 0; i < numRoutes; i++ {
+			routes = append(routes, rte.Route{
+				Method: "GET",
+				Path:   fmt.Sprintf("/route/%d", i),
+				Handler: func(w http.ResponseWriter, r *http.Request) {
+					_, _ = fmt.Fprintf(w, "v%d", v)
+				},
+			})
+		}
+		return routes
+	}
+
+	rt, err := rte.NewReloadable(version(0), func(tbl *rte.Table) {
+		tbl.Default = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		stop = make(chan struct{})
+	)
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				w := httptest.NewRecorder()
+				rt.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/route/%d", (g+i)%numRoutes), nil))
+				if w.Code != http.StatusOK {
+					t.Errorf("got %v; saw a partially built table", w.Code)
+					return
+				}
+			}
+		}(g)
+	}
+
+	for v := 1; v <= 100; v++ {
+		if err := rt.Reload(version(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, httptest.NewRequest("GET", "/route/0", nil))
+	if w.Body.String() != "v100" {
+		t.Fatalf("want v100 but got %q", w.Body.String())
+	}
+
+	t.Run("failed reload", func(t *testing.T) {
+		if err := rt.Reload([]rte.Route{{Method: "GET", Path: "missing-slash", Handler: http.NotFoundHandler()}}); err == nil {
+			t.Fatal("expected an error")
+		}
+
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest("GET", "/route/0", nil))
+		if w.Body.String() != "v100" {
+			t.Fatalf("want v100 but got %q", w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+		if w.Code != http.StatusTeapot {
+			t.Fatalf("want the configured default but got %v", w.Code)
+		}
+	})
+}
@@ -5,9 +5,13 @@ package rte
 
 import (
 	"fmt"
+	"go/token"
 	"net/http"
-	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/jwilner/rte/internal/funcs"
 )
@@ -40,9 +44,48 @@ func (f MiddlewareFunc) Handle(w http.ResponseWriter, r *http.Request, next http
 
 // Route is data for routing to a handler
 type Route struct {
-	Method, Path string
-	Handler      interface{}
-	Middleware   Middleware
+	Method string
+	// Path is the route's path template. Its final segment may be a variable followed by an optional format, e.g.
+	// "/posts/:id.:format", in which case the segment is split at its last '.': "/posts/1.json" yields "1" and "json",
+	// and "/posts/1" yields "1" and "". The format is an additional path variable; the route otherwise behaves like
+	// "/posts/:id".
+	//
+	// A variable may be constrained to a length in bytes (as sent, i.e. possibly percent-encoded) with a suffix, e.g.
+	// "/s/:code{6}" for exactly 6 or "/s/:code{4,8}" for 4 to 8; a request whose variable has a different length doesn't
+	// match the route. A variable followed by a format is constrained without it, e.g. "/posts/:id{1,3}.:format"
+	// matches "/posts/1.json". Routes sharing a path (e.g. for different methods) must have the same constraints.
+	Path       string
+	Handler    interface{}
+	Middleware Middleware
+	// Priority makes explicit which of two otherwise conflicting routes should be preferred -- e.g. "GET /users/me"
+	// and "GET /users/:id". Static segments don't implicitly take precedence over wildcards: routes which would obscure
+	// each other are only permitted if their priorities differ, so a static route meant to win over a wildcard sibling
+	// needs the higher priority. When both could match a request, the one with the higher priority is tried first,
+	// falling back to the other if it turns out not to match. Priority is otherwise irrelevant.
+	Priority int
+	// CaseInsensitive makes the route's path match requests regardless of (ASCII) case -- e.g. "/Content/Page" matches
+	// "GET /content/page"; path variables retain the case of the request. Case insensitive routes are only consulted if
+	// no case sensitive route matches.
+	CaseInsensitive bool
+	// Name optionally identifies the route, e.g. for ExportConstants; if set, it must be a Go identifier and unique
+	// within the table.
+	Name string
+	// OpenAPI optionally describes the route for Table.OpenAPI
+	OpenAPI *OpenAPIOperation
+	// Validate, if set, checks each matched request before the handler (but within any middleware) is invoked; if it
+	// returns an error, the handler is skipped and the error is passed to the table's OnError with a 400.
+	Validate func(r *http.Request) error
+	// ContentType, if set, is the default Content-Type of the route's responses; the header is set before the handler
+	// (but within any middleware) is invoked, so the handler can still override it.
+	ContentType string
+	// OnParseError, if set, responds to requests whose path variables can't be parsed or decoded for the route in place
+	// of the table's OnParseError -- e.g. with a 422 detailing the offending variable.
+	OnParseError func(w http.ResponseWriter, r *http.Request, err *ParseError)
+	// NoBodyLimit exempts the route from MaxBodyBytes, e.g. for an endpoint accepting large streaming uploads.
+	NoBodyLimit bool
+	// Doc optionally describes the route, e.g. for a help endpoint listing the routes (see RoutesUnderPrefix) with
+	// their path templates; it's retrievable by request with Table.Doc. It doesn't affect routing.
+	Doc string
 }
 
 func (r Route) String() string {
@@ -66,7 +109,7 @@ const (
 	ErrTypeNilHandler
 	// ErrTypePathEmpty means a path was empty
 	ErrTypePathEmpty
-	// ErrTypeNoInitialSlash means the path was missing the initial slash
+	// ErrTypeNoInitialSlash means the path was missing the initial slash (or separator, see WithSeparator)
 	ErrTypeNoInitialSlash
 	// ErrTypeInvalidSegment means there was an invalid segment within a path
 	ErrTypeInvalidSegment
@@ -80,6 +123,12 @@ const (
 	ErrTypeParamCountMismatch
 	// ErrTypeConflictingRoutes is returned when a route would be obscured by a wildcard.
 	ErrTypeConflictingRoutes
+	// ErrTypeInvalidName means a route's name isn't a Go identifier
+	ErrTypeInvalidName
+	// ErrTypeDuplicateName means more than one route has the same name
+	ErrTypeDuplicateName
+	// ErrTypeDuplicateVarName means a path has more than one variable with the same (non-empty) name
+	ErrTypeDuplicateVarName
 )
 
 // TableError encapsulates table construction errors
@@ -94,115 +143,472 @@ func (e *TableError) Error() string {
 }
 
 // Must builds routes into a Table and panics if there's an error
-func Must(routes []Route) *Table {
-	t, e := New(routes)
+func Must(routes []Route, opts ...Option) *Table {
+	t, e := New(routes, opts...)
 	if e != nil {
 		panic(e.Error())
 	}
 	return t
 }
 
-var (
-	regexpNormalize  = regexp.MustCompile(`:[^/]*`)
-	regexpInvalidVar = regexp.MustCompile(`[^/]:`)
-)
+// Option configures a Table as it's built by New
+type Option func(*Table)
+
+// WithSeparator sets the byte separating path segments, which is '/' by default. Routes must begin with the separator
+// and variables must occupy whole segments, e.g. with '.' a route might be ".users.:user_id.posts". The separator
+// applies both to parsing routes and matching requests.
+func WithSeparator(sep byte) Option {
+	return func(t *Table) {
+		t.sep = sep
+	}
+}
+
+// WithConverter registers a Converter for the table, permitting routes with handlers of types rte doesn't otherwise
+// support. Converters are tried in the order registered, before rte's built-in conversions. Only the table being built
+// is affected, so different tables may support different handler types. Note that rte.Routes only accepts the built-in
+// handler types; routes with custom handlers should be constructed directly.
+func WithConverter(c Converter) Option {
+	return func(t *Table) {
+		t.converters = append(t.converters, c)
+	}
+}
+
+// WithMatchCounters enables counting the requests dispatched to each route, which are reported by MatchCounts; e.g. to
+// find routes which are never used. Counting is off by default to avoid its (small) cost.
+func WithMatchCounters() Option {
+	return func(t *Table) {
+		t.countMatches = true
+	}
+}
+
+// WithDuplicateResolver makes New resolve a route with the same method and path (ignoring variable names) as an earlier
+// one with resolve, rather than failing with ErrTypeDuplicateHandler -- e.g. to let later routes override earlier
+// ones. The route resolve returns, usually one of the two, is registered in place of both at the later route's
+// position. For example, a last-wins resolver:
+//
+//	rte.WithDuplicateResolver(func(existing, new rte.Route) rte.Route { return new })
+func WithDuplicateResolver(resolve func(existing, new Route) Route) Option {
+	return func(t *Table) {
+		t.resolveDuplicate = resolve
+	}
+}
+
+// PathVars holds the values of a request's path variables in order; unused entries are empty.
+type PathVars = funcs.PathVars
+
+// VarsHandler is the form every handler is converted to; it receives the matched path variables without allocating.
+type VarsHandler func(w http.ResponseWriter, r *http.Request, vars PathVars)
+
+// Converter converts a handler of a custom type to a VarsHandler, also returning the number of path variables the
+// handler requires (or 0 if it doesn't care). It returns false if it doesn't support the provided value.
+type Converter func(handler interface{}) (VarsHandler, int, bool)
 
 // New builds routes into a Table or returns an error
-func New(routes []Route) (*Table, error) {
+func New(routes []Route, opts ...Option) (*Table, error) {
+	t := newTable(opts)
+	for i, r := range routes {
+		if err := t.add(i, r); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// CheckConflicts validates routes just as New does, but rather than stopping at the first problem, it returns every
+// problem found -- each is a *TableError. It's intended for tests asserting that route sets (e.g. those defined by
+// different packages) are valid and compatible with each other:
+//
+//	if errs := rte.CheckConflicts(append(users.Routes(), billing.Routes()...)); len(errs) > 0 {
+//		t.Fatal(errs)
+//	}
+//
+// A route which fails validation isn't considered when checking subsequent routes.
+func CheckConflicts(routes []Route, opts ...Option) []error {
+	var (
+		t      = newTable(opts)
+		errs   []error
+		passed []Route
+	)
+	for i, r := range routes {
+		if err := t.add(i, r); err != nil {
+			errs = append(errs, err)
+
+			// a conflicting route has already been inserted by the time its conflict is detected, so rebuild from
+			// scratch without it
+			t = newTable(opts)
+			for j, p := range passed {
+				_ = t.add(j, p)
+			}
+			continue
+		}
+		passed = append(passed, r)
+	}
+	return errs
+}
+
+func newTable(opts []Option) *Table {
 	t := &Table{
 		root:    newNode("", 0),
 		Default: http.NotFoundHandler(),
+		names:   make(map[string]struct{}),
+		sep:     '/',
 	}
+	for _, o := range opts {
+		o(t)
+	}
+	return t
+}
 
-	seenMethods := map[string]bool{}
-	maxVars := len(funcs.PathVars{})
-	for i, r := range routes {
-		if r.Method == "" {
-			return nil, &TableError{Type: ErrTypeMethodEmpty, Idx: i, Route: r, Msg: "method cannot be empty"}
-		}
+// add validates the route and inserts it into the table
+func (t *Table) add(i int, r Route) *TableError {
+	if r.Method == "" {
+		return &TableError{Type: ErrTypeMethodEmpty, Idx: i, Route: r, Msg: "method cannot be empty"}
+	}
+
+	if r.Handler == nil {
+		return &TableError{Type: ErrTypeNilHandler, Idx: i, Route: r, Msg: "handler cannot be nil"}
+	}
+
+	if r.Path == "" {
+		return &TableError{Type: ErrTypePathEmpty, Idx: i, Route: r, Msg: "path cannot be empty"}
+	}
+
+	if r.Path[0] != t.sep {
+		return &TableError{Type: ErrTypeNoInitialSlash, Idx: i, Route: r, Msg: "no initial slash"}
+	}
+
+	path, format, ok := cutFormat(r.Path, t.sep)
+	if !ok {
+		return &TableError{Type: ErrTypeInvalidSegment, Idx: i, Route: r, Msg: "invalid segment"}
+	}
 
-		if r.Handler == nil {
-			return nil, &TableError{Type: ErrTypeNilHandler, Idx: i, Route: r, Msg: "handler cannot be nil"}
+	scanned, ok := scanPath(path, t.sep)
+	if !ok {
+		return &TableError{Type: ErrTypeInvalidSegment, Idx: i, Route: r, Msg: "invalid segment"}
+	}
+	if format {
+		scanned.names = append(scanned.names, r.Path[len(path)+2:])
+		if n := len(scanned.lengths); n > 0 && scanned.lengths[n-1].max != 0 {
+			scanned.lengths[n-1].format = true
 		}
+	}
+
+	numPathParams := len(scanned.names)
 
-		if r.Path == "" {
-			return nil, &TableError{Type: ErrTypePathEmpty, Idx: i, Route: r, Msg: "path cannot be empty"}
+	if maxVars := len(funcs.PathVars{}); numPathParams > maxVars {
+		return &TableError{
+			Type:  ErrTypeOutOfRange,
+			Idx:   i,
+			Route: r,
+			Msg:   fmt.Sprintf("path has more than %v parameters", maxVars),
 		}
+	}
 
-		if r.Path[0] != '/' {
-			return nil, &TableError{Type: ErrTypeNoInitialSlash, Idx: i, Route: r, Msg: "no initial slash"}
+	for j, name := range scanned.names {
+		for _, prev := range scanned.names[:j] {
+			if name != "" && name == prev {
+				return &TableError{
+					Type:  ErrTypeDuplicateVarName,
+					Idx:   i,
+					Route: r,
+					Msg:   fmt.Sprintf("variable name %q is used more than once", name),
+				}
+			}
 		}
+	}
 
-		if strings.Contains(r.Path, "*") || regexpInvalidVar.MatchString(r.Path) {
-			return nil, &TableError{Type: ErrTypeInvalidSegment, Idx: i, Route: r, Msg: "invalid segment"}
+	root, normalized := t.root, scanned.normalized
+	if r.CaseInsensitive {
+		if t.foldRoot == nil {
+			t.foldRoot = newNode("", 0)
 		}
+		root, normalized = t.foldRoot, toLowerASCII(normalized)
+	}
 
-		var numPathParams int
-		for _, c := range r.Path {
-			if c == ':' {
-				numPathParams++
+	if t.resolveDuplicate != nil {
+		if existing, ok := takeHandler(root, normalized, r.Method); ok {
+			if existing.Route.Name != "" {
+				delete(t.names, existing.Route.Name)
 			}
+			return t.add(i, t.resolveDuplicate(existing.Route, r))
 		}
+	}
 
-		if numPathParams > maxVars {
-			return nil, &TableError{
-				Type:  ErrTypeOutOfRange,
-				Idx:   i,
-				Route: r,
-				Msg:   fmt.Sprintf("path has more than %v parameters", maxVars),
-			}
+	onParseErr := t.parseErrorHandler(r)
+	h, numHandlerParams, ok := t.convert(r.Handler, onParseErr)
+	if !ok {
+		return &TableError{
+			Type:  ErrTypeConversionFailure,
+			Idx:   i,
+			Route: r,
+			Msg:   fmt.Sprintf("handler has an unsupported signature: %T", r.Handler),
 		}
+	} else if numHandlerParams != 0 && numPathParams != numHandlerParams {
+		return &TableError{
+			Type:  ErrTypeParamCountMismatch,
+			Idx:   i,
+			Route: r,
+			Msg:   "path and handler have different numbers of parameters",
+		}
+	}
 
-		h, numHandlerParams, ok := funcs.Convert(r.Handler)
-		if !ok {
-			return nil, &TableError{
-				Type:  ErrTypeConversionFailure,
-				Idx:   i,
-				Route: r,
-				Msg:   fmt.Sprintf("handler has an unsupported signature: %T", r.Handler),
-			}
-		} else if numHandlerParams != 0 && numPathParams != numHandlerParams {
-			return nil, &TableError{
-				Type:  ErrTypeParamCountMismatch,
+	raw := h
+
+	if r.ContentType != "" {
+		h = contentType(h, r.ContentType)
+	}
+
+	if r.Validate != nil {
+		h = validate(h, r.Validate, t.handlerError)
+	}
+
+	if r.Middleware != nil {
+		h = applyMiddleware(h, r.Middleware)
+	}
+
+	if r.Name != "" {
+		if !token.IsIdentifier(r.Name) {
+			return &TableError{Type: ErrTypeInvalidName, Idx: i, Route: r, Msg: "name must be a Go identifier"}
+		}
+		if _, ok := t.names[r.Name]; ok {
+			return &TableError{
+				Type:  ErrTypeDuplicateName,
 				Idx:   i,
 				Route: r,
-				Msg:   "path and handler have different numbers of parameters",
+				Msg:   fmt.Sprintf("name %q is already in use", r.Name),
 			}
 		}
+	}
+
+	var count *atomic.Int64
+	if t.countMatches {
+		count = new(atomic.Int64)
+		h = countMatches(h, count)
+	}
+
+	if t.matchContext {
+		h = withMatch(h, r, i, scanned.names)
+	}
+
+	mh := methodHandler{
+		Method:   r.Method,
+		Flag:     t.methodFlag(r.Method),
+		Handler:  h,
+		raw:      raw,
+		Route:    r,
+		Idx:      i,
+		format:   format,
+		varNames: scanned.names,
+		lengths:  scanned.lengths,
+		kinds:    funcs.TypedKinds(r.Handler),
+		count:    count,
+
+		onParseError: onParseErr,
+	}
+	err := insert(root, normalized, mh, r.Priority)
+	if err == nil {
+		err = checkConflicts(root, normalized)
+	}
+	if err != nil {
+		err.Route = r
+		err.Idx = i
+		return err
+	}
+
+	if r.Name != "" {
+		t.names[r.Name] = struct{}{}
+	}
 
-		if r.Middleware != nil {
-			h = applyMiddleware(h, r.Middleware)
+	return nil
+}
+
+// convert converts the handler with the table's converters, falling back to the built-in conversions, whose typed
+// handlers report parse errors to onParseErr
+func (t *Table) convert(i interface{}, onParseErr funcs.ParseErrorHandler) (funcs.Handler, int, bool) {
+	for _, c := range t.converters {
+		if h, n, ok := c(i); ok {
+			return funcs.Handler(h), n, true
 		}
+	}
+	return convertBuiltin(i, onParseErr, t.handlerError, http.HandlerFunc(t.serveDefault))
+}
 
-		if !seenMethods[r.Method] {
-			seenMethods[r.Method] = true
-			t.methods = append(t.methods, r.Method)
-			if r.Method == MethodAny {
-				// we'll want to always check for MethodAny, too, in our subtrees
-				t.methodMask = 1 << uint(len(t.methods)-1)
-			}
+// cutFormat removes the optional format variable from the end of the path, if there is one, reporting whether it was
+// found and whether it's well formed
+func cutFormat(path string, sep byte) (string, bool, bool) {
+	seg := path[strings.LastIndexByte(path, sep)+1:]
+	i := strings.Index(seg, ".:")
+	if i < 0 {
+		return path, false, true
+	}
+	name, format := seg[:i], seg[i+2:]
+	ok := len(name) > 1 && name[0] == ':' && format != "" && !strings.ContainsAny(format, ".:")
+	return path[:len(path)-len(seg)+i], true, ok
+}
+
+// splitFormat splits the format from the last of the n variables at its last '.', returning the new number of
+// variables
+func splitFormat(vars []string, n int) int {
+	if i := strings.LastIndexByte(vars[n-1], '.'); i >= 0 {
+		vars[n-1], vars[n] = vars[n-1][:i], vars[n-1][i+1:]
+	}
+	return n + 1
+}
+
+// varLength constrains the length of a variable; the zero value is unconstrained
+type varLength struct {
+	min, max int
+	// format indicates the variable is followed by a format, which doesn't count towards its length
+	format bool
+}
+
+func sameLengths(a, b []varLength) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
+	}
+	return true
+}
 
-		var methodFlag uint
-		for i, m := range t.methods {
-			if m == r.Method {
-				methodFlag = 1 << uint(i)
+// fits reports whether the variables satisfy the length constraints
+func fits(lengths []varLength, vars []string) bool {
+	for i, l := range lengths {
+		v := vars[i]
+		if l.format {
+			if j := strings.LastIndexByte(v, '.'); j >= 0 {
+				v = v[:j]
 			}
 		}
+		if l.max != 0 && (len(v) < l.min || len(v) > l.max) {
+			return false
+		}
+	}
+	return true
+}
 
-		normalized := regexpNormalize.ReplaceAllString(r.Path, "*")
-		if err := insert(t.root, methodFlag, r.Method, normalized, h); err != nil {
-			err.Route = r
-			err.Idx = i
-			return nil, err
+// scannedPath is a route's path as parsed by scanPath
+type scannedPath struct {
+	// normalized is the path with every variable segment replaced with a single '*'
+	normalized string
+	// names are the names of the variables, in order
+	names []string
+	// lengths are the variables' length constraints, or nil if none are constrained
+	lengths []varLength
+}
+
+// scanPath parses the path in a single pass, reporting whether it's valid: variables must begin their segments, and
+// '*' is reserved. The normalized path is populated even if the path is invalid.
+func scanPath(path string, sep byte) (scannedPath, bool) {
+	var (
+		b           strings.Builder
+		p           scannedPath
+		constrained bool
+	)
+	ok := !strings.Contains(path, "*")
+	for i := 0; i < len(path); i++ {
+		if path[i] != ':' {
+			b.WriteByte(path[i])
+			continue
+		}
+		if i > 0 && path[i-1] != sep {
+			ok = false
+		}
+
+		end := i + 1
+		for end < len(path) && path[end] != sep {
+			end++
 		}
+		name, l, valid := scanVar(path[i+1 : end])
+		ok = ok && valid
+		constrained = constrained || l != varLength{}
+		p.names = append(p.names, name)
+		p.lengths = append(p.lengths, l)
+
+		b.WriteByte('*')
+		i = end - 1
 	}
 
-	return t, nil
+	p.normalized = b.String()
+	if !constrained {
+		p.lengths = nil
+	}
+	return p, ok
 }
 
-func insert(node *node, methodFlag uint, method, path string, h funcs.Handler) *TableError {
-	node.methods |= methodFlag // mark this node as containing our current method
+// scanVar parses a variable segment (without its ':'), e.g. "code" or "code{4,8}"
+func scanVar(seg string) (string, varLength, bool) {
+	name, spec, constrained := strings.Cut(seg, "{")
+	if strings.IndexByte(name, ':') >= 0 {
+		return name, varLength{}, false
+	}
+	if !constrained {
+		return name, varLength{}, true
+	}
+	if name == "" || !strings.HasSuffix(spec, "}") {
+		return name, varLength{}, false
+	}
+
+	minS, maxS, isRange := strings.Cut(spec[:len(spec)-1], ",")
+	if !isRange {
+		maxS = minS
+	}
+	var (
+		l          varLength
+		err1, err2 error
+	)
+	l.min, err1 = strconv.Atoi(minS)
+	l.max, err2 = strconv.Atoi(maxS)
+	if err1 != nil || err2 != nil || l.min < 1 || l.max < l.min {
+		return name, varLength{}, false
+	}
+	return name, l, true
+}
+
+// normalize replaces every variable segment in the path with a single '*'
+func normalize(path string, sep byte) string {
+	p, _ := scanPath(path, sep)
+	return p.normalized
+}
+
+// toLowerASCII lower cases ASCII letters, leaving all other bytes untouched
+func toLowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		b[i] = lowerASCII(c)
+	}
+	return string(b)
+}
+
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// methodFlag returns the bit flag for the method, registering the method if it hasn't been seen before
+func (t *Table) methodFlag(method string) uint {
+	for i, m := range t.methods {
+		if m == method {
+			return 1 << uint(i)
+		}
+	}
+
+	t.methods = append(t.methods, method)
+	flag := uint(1) << uint(len(t.methods)-1)
+	if method == MethodAny {
+		// we'll want to always check for MethodAny, too, in our subtrees
+		t.methodMask = flag
+	}
+	return flag
+}
+
+func insert(node *node, path string, mh methodHandler, priority int) *TableError {
+	node.methods |= mh.Flag // mark this node as containing our current method
 
 	pathIdx := 0
 
@@ -219,7 +625,8 @@ func insert(node *node, methodFlag uint, method, path string, h funcs.Handler) *
 		// label has finished
 		if labelIdx == len(child.label) {
 			node = child
-			node.methods |= methodFlag // mark this new node as containing our current method
+			node.methods |= mh.Flag // mark this new node as containing our current method
+			node.raisePriority(priority)
 
 			if pathIdx == len(path) { // label and path are coincident -- probably multiple methods
 				break
@@ -232,55 +639,75 @@ func insert(node *node, methodFlag uint, method, path string, h funcs.Handler) *
 		if pathIdx == len(path) {
 			// note that the order in which nodes are added is significant here, because we're about to
 			// mutate labels and that's what things are internally keyed by -- always add parents first.
-			newChild := newNode(child.label[:labelIdx], methodFlag|child.methods)
-			newChild.setHandler(method, h)
+			newChild := newNode(child.label[:labelIdx], mh.Flag|child.methods)
+			newChild.priority = child.priority
+			newChild.raisePriority(priority)
+			newChild.setHandler(mh)
 			node.addChild(newChild)
 
 			child.label = child.label[labelIdx:]
 			newChild.addChild(child)
-			return nil // no conflict possible
+			return nil
 		}
 
 		// path is different from label in middle of label -- split
 
 		// note that the order in which nodes are added is significant here, because we're about to
 		// mutate labels and that's what things are internally keyed by -- always add parents first.
-		branch := newNode(child.label[:labelIdx], child.methods|methodFlag)
+		branch := newNode(child.label[:labelIdx], child.methods|mh.Flag)
+		branch.priority = child.priority
+		branch.raisePriority(priority)
 		node.addChild(branch)
 
-		newN := newNode(path[pathIdx:], methodFlag)
-		newN.setHandler(method, h)
+		newN := newNode(path[pathIdx:], mh.Flag)
+		newN.priority = priority
+		newN.setHandler(mh)
 
 		child.label = child.label[labelIdx:]
 
 		branch.addChild(newN) // error is impossible b/c we know branch has no children
 		branch.addChild(child)
 
-		return checkConflict(path[:pathIdx], branch)
+		return nil
 	}
 
 	if pathIdx == len(path) {
-		if node.handler(method) != nil {
+		if node.handler(mh.Method) != nil {
 			return &TableError{Type: ErrTypeDuplicateHandler, Msg: "duplicate handler"}
 		}
-		node.setHandler(method, h)
+		if len(node.hndlrs) > 0 && !sameLengths(node.lengths, mh.lengths) {
+			return &TableError{
+				Type: ErrTypeConflictingRoutes,
+				Msg:  "routes with the same path have different length constraints",
+			}
+		}
+		node.setHandler(mh)
 		return nil
 	}
 
 	// we've still got path to consume -- add a new child
-	ch := newNode(path[pathIdx:], methodFlag)
-	ch.setHandler(method, h)
+	ch := newNode(path[pathIdx:], mh.Flag)
+	ch.priority = priority
+	ch.setHandler(mh)
 	node.addChild(ch)
 
-	return checkConflict(path[:pathIdx], node)
+	return nil
 }
 
 type node struct {
 	// methods is a bit mask represent the different HTTP methods available in this subtree
-	methods  uint
+	methods uint
+	// own is a bit mask of the methods with handlers on this node itself
+	own uint
+	// priority is the highest priority of any route in this subtree
+	priority int
 	children []*node
-	label    string
-	hndlrs   []methodHandler
+	// wild is the child which is a wildcard, if any
+	wild   *node
+	label  string
+	hndlrs []methodHandler
+	// lengths constrains the lengths of the variables of requests matching this node, if it's not nil
+	lengths []varLength
 }
 
 func newNode(label string, methodFlags uint) *node {
@@ -288,6 +715,9 @@ func newNode(label string, methodFlags uint) *node {
 }
 
 func (n *node) addChild(n2 *node) {
+	if n2.label[0] == '*' {
+		n.wild = n2
+	}
 	for i, c := range n.children {
 		if c.label[0] == n2.label[0] {
 			n.children[i] = n2
@@ -304,6 +734,26 @@ func (n *node) addChild(n2 *node) {
 	n.children = newC
 }
 
+// priorityFor returns the highest priority of the node's own routes for the methods
+func (n *node) priorityFor(methodMask uint) int {
+	var (
+		priority int
+		found    bool
+	)
+	for i := range n.hndlrs {
+		if mh := &n.hndlrs[i]; mh.Flag&methodMask != 0 && (!found || mh.Route.Priority > priority) {
+			priority, found = mh.Route.Priority, true
+		}
+	}
+	return priority
+}
+
+func (n *node) raisePriority(priority int) {
+	if priority > n.priority {
+		n.priority = priority
+	}
+}
+
 func (n *node) child(b byte) *node {
 	for _, c := range n.children {
 		if c.label[0] == b {
@@ -313,6 +763,30 @@ func (n *node) child(b byte) *node {
 	return nil
 }
 
+func countMatches(h funcs.Handler, count *atomic.Int64) funcs.Handler {
+	return func(w http.ResponseWriter, r *http.Request, pathVars funcs.PathVars) {
+		count.Add(1)
+		h(w, r, pathVars)
+	}
+}
+
+func contentType(h funcs.Handler, ct string) funcs.Handler {
+	return func(w http.ResponseWriter, r *http.Request, pathVars funcs.PathVars) {
+		w.Header().Set("Content-Type", ct)
+		h(w, r, pathVars)
+	}
+}
+
+func validate(h funcs.Handler, v func(r *http.Request) error, onErr funcs.ErrorHandler) funcs.Handler {
+	return func(w http.ResponseWriter, r *http.Request, pathVars funcs.PathVars) {
+		if err := v(r); err != nil {
+			onErr(w, r, http.StatusBadRequest, err)
+			return
+		}
+		h(w, r, pathVars)
+	}
+}
+
 func applyMiddleware(h funcs.Handler, mw Middleware) funcs.Handler {
 	return func(w http.ResponseWriter, r *http.Request, pathVars funcs.PathVars) {
 		mw.Handle(w, r, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -323,197 +797,819 @@ func applyMiddleware(h funcs.Handler, mw Middleware) funcs.Handler {
 
 // Table manages the routing table and a default handler
 type Table struct {
-	Default    http.Handler
-	root       *node
+	Default http.Handler
+	// DefaultByPrefix, if set, handles requests no route matches whose (decoded) paths begin with one of its prefixes
+	// in place of Default -- e.g. so that misses under "/api/" are answered with JSON and others with HTML. If several
+	// prefixes match, the longest is chosen, or the first listed of those equally long.
+	DefaultByPrefix []PrefixHandler
+	// NormalizeUnicode, if set, is applied to each request's path before matching; it receives the path as sent by the
+	// client (i.e. possibly percent-encoded). It can be used to make e.g. NFD paths match NFC routes -- rte does not
+	// provide Unicode normalization itself to avoid a dependency; golang.org/x/text/unicode/norm is a good choice.
+	NormalizeUnicode func(path string) string
+	// StripMatrixParams, if set, causes matrix parameters -- everything from a ';' to the end of its segment, e.g.
+	// ";jsessionid=abc" in "/users/42;jsessionid=abc" -- to be ignored when matching.
+	StripMatrixParams bool
+	// OptionsHandler, if set, handles every OPTIONS request which isn't matched by an explicit OPTIONS route, regardless
+	// of path -- e.g. to respond uniformly to CORS preflight requests. It takes precedence over MethodAny routes, unless
+	// MethodAnyFirst is set, and the Default handler.
+	OptionsHandler http.Handler
+	// AutoHead, if set, serves HEAD requests which aren't matched by an explicit HEAD route with the path's GET route,
+	// discarding the response body. Like OptionsHandler, it takes precedence over MethodAny routes unless MethodAnyFirst
+	// is set.
+	AutoHead bool
+	// MethodAnyFirst controls how MethodAny routes interact with OptionsHandler and AutoHead. By default, the
+	// precedence for an OPTIONS or HEAD request is:
+	//
+	//	explicit route > OptionsHandler or AutoHead > MethodAny route > MethodNotAllowed > Default
+	//
+	// If set, MethodAny routes intercept the automatically handled methods, too:
+	//
+	//	explicit route > MethodAny route > OptionsHandler or AutoHead > MethodNotAllowed > Default
+	//
+	// In either case, MethodAny routes handle OPTIONS and HEAD requests when the corresponding automatic handling is
+	// disabled or doesn't apply, e.g. a HEAD request for a path without a GET route.
+	MethodAnyFirst bool
+	// MethodNotAllowed, if set, handles requests whose path matches routes for other methods only -- e.g. a DELETE
+	// when only GET is routed -- rather than Default; the Allow header lists the other methods in a canonical order
+	// (GET, HEAD, POST, PUT, PATCH, DELETE, then others alphabetically). Requests whose path doesn't match any route
+	// still go to Default.
+	MethodNotAllowed http.Handler
+	// RequireResponse, if set, guards against handlers which neglect to write a response: if neither WriteHeader nor
+	// Write has been called when the handler returns, MissingResponseStatus is written rather than net/http's implicit
+	// 200.
+	RequireResponse bool
+	// MissingResponseStatus is the status written when RequireResponse is set and a handler writes nothing; it
+	// defaults to 500.
+	MissingResponseStatus int
+	// UnescapeVars, if set, causes path variables to be percent-decoded (per url.PathUnescape) before they're provided
+	// to handlers; a malformed encoding, e.g. "%zz", is treated as a parse error. Vars and Match are unaffected.
+	UnescapeVars bool
+	// DecodePlusAsSpace, if set, causes path variables to be decoded as UnescapeVars does, but with '+' decoded as a
+	// space (per url.QueryUnescape), so that requests from legacy clients which form encode path segments match as
+	// intended -- e.g. "/q/hello+world" provides "hello world". This is non-standard: in paths, '+' is otherwise a
+	// literal, and a literal '+' must then be sent as "%2B".
+	DecodePlusAsSpace bool
+	// OnParseError, if set, responds to requests whose path variables can't be parsed as the types the matched handler
+	// requires (or, with UnescapeVars, decoded), unless the route has its own (see Route.OnParseError); by default, the
+	// response is a 400 with the error's message.
+	OnParseError func(w http.ResponseWriter, r *http.Request, err *ParseError)
+	// OnError, if set, responds to requests whose handler -- of a form returning (int, error) -- returned a non-nil
+	// error, receiving the status returned alongside it, and to requests failing a route's Validate, with a 400; by
+	// default, the response is the status (or a 500 if it's zero) with its standard text, so as not to reveal the
+	// error.
+	OnError func(w http.ResponseWriter, r *http.Request, status int, err error)
+
+	root *node
+	// foldRoot holds the case insensitive routes, if there are any
+	foldRoot   *node
+	sep        byte
+	converters []Converter
 	methods    []string
 	methodMask uint
+	names      map[string]struct{}
+
+	// countMatches indicates whether or not requests dispatched to each route should be counted
+	countMatches bool
+	// matchContext indicates whether or not the Match should be stored in each request's context
+	matchContext bool
+	// resolveDuplicate, if set, chooses between routes with the same method and path rather than failing
+	resolveDuplicate func(existing, new Route) Route
+	// observers are invoked after each request is served; see Observe
+	observers []func(r *http.Request, status int, duration time.Duration)
+}
+
+// Observe registers a function to be invoked after every request the table serves -- whether it matched a route or
+// was handled by e.g. Default -- with the response's status and how long serving it took; e.g. for access logging.
+// Unlike middleware, observers can neither short-circuit requests nor modify responses. Observers are invoked in the
+// order they're registered, on the request's goroutine, so slow work should be handed off. Observe must not be called
+// concurrently with serving requests.
+func (t *Table) Observe(observer func(r *http.Request, status int, duration time.Duration)) {
+	t.observers = append(t.observers, observer)
 }
 
+// ServeHTTP dispatches the request to the route matching its method and escaped path, i.e. its request URI without
+// the query string.
 func (t *Table) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	methods := t.acceptMethods(r)
-	if methods == 0 {
-		t.Default.ServeHTTP(w, r)
+	if t.observers != nil {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		t.serveRequired(sw, r)
+		duration := time.Since(start)
+
+		status := sw.status
+		if status == 0 {
+			// nothing was written, for which net/http responds with a 200
+			status = http.StatusOK
+		}
+		for _, o := range t.observers {
+			o(r, status, duration)
+		}
 		return
 	}
+	t.serveRequired(w, r)
+}
 
-	var variables funcs.PathVars
-	if _, node := t.matchPath(methods, r.RequestURI, variables[:]); node != nil {
-		if h := node.handler(r.Method); h != nil {
-			h(w, r, variables)
+// serveRequired serves the request, enforcing RequireResponse
+func (t *Table) serveRequired(w http.ResponseWriter, r *http.Request) {
+	if t.RequireResponse {
+		tw := &trackingWriter{ResponseWriter: w}
+		t.serve(tw, r)
+		if !tw.written {
+			status := t.MissingResponseStatus
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			w.WriteHeader(status)
+		}
+		return
+	}
+	t.serve(w, r)
+}
+
+func (t *Table) serve(w http.ResponseWriter, r *http.Request) {
+	var (
+		variables funcs.PathVars
+		numVars   int
+		node      *node
+	)
+	flag, methods := t.acceptMethods(r.Method)
+	if methods != 0 {
+		numVars, node = t.matchPath(methods, t.requestPath(r), variables[:], nil)
+	}
+
+	mh, head := t.handlerFor(node, r.Method, flag)
+	if mh == nil && r.Method == http.MethodOptions && t.OptionsHandler != nil {
+		t.OptionsHandler.ServeHTTP(w, r)
+		return
+	}
+	if head {
+		w = headWriter{w}
+	}
+
+	if mh != nil {
+		if mh.format {
+			numVars = splitFormat(variables[:], numVars)
+		}
+		if t.decodesVars() && !t.unescapeVars(w, r, mh.onParseError, variables[:numVars]) {
 			return
 		}
-		if h := node.handler(MethodAny); h != nil {
-			h(w, r, variables)
+		mh.Handler(w, r, variables)
+		return
+	}
+
+	if t.MethodNotAllowed != nil {
+		if allow := t.allowed(r); allow != "" {
+			w.Header().Set("Allow", allow)
+			t.MethodNotAllowed.ServeHTTP(w, r)
 			return
 		}
 	}
 
-	t.Default.ServeHTTP(w, r)
+	t.defaultHandler(r).ServeHTTP(w, r)
+}
+
+// PrefixHandler pairs a path prefix with the handler for requests under it; see Table.DefaultByPrefix.
+type PrefixHandler struct {
+	Prefix  string
+	Handler http.Handler
+}
+
+// serveDefault responds to a request no route matches, deferring to Default and DefaultByPrefix as of request time
+func (t *Table) serveDefault(w http.ResponseWriter, r *http.Request) {
+	t.defaultHandler(r).ServeHTTP(w, r)
+}
+
+// defaultHandler returns the handler for a request no route matches
+func (t *Table) defaultHandler(r *http.Request) http.Handler {
+	h, longest := t.Default, -1
+	for _, p := range t.DefaultByPrefix {
+		if len(p.Prefix) > longest && strings.HasPrefix(r.URL.Path, p.Prefix) {
+			h, longest = p.Handler, len(p.Prefix)
+		}
+	}
+	return h
+}
+
+// handlerError responds to a handler's returned error, deferring to the table's OnError as of request time
+func (t *Table) handlerError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if t.OnError != nil {
+		t.OnError(w, r, status, err)
+		return
+	}
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// allowed returns the methods which could serve the request's path, in the format of an Allow header; it's empty if
+// the path matches no route for any method
+func (t *Table) allowed(r *http.Request) string {
+	var variables funcs.PathVars
+	_, node := t.matchPath(1<<uint(len(t.methods))-1, t.requestPath(r), variables[:], nil)
+	if node == nil {
+		return ""
+	}
+
+	var methods []string
+	for _, mh := range node.hndlrs {
+		if mh.Method != MethodAny {
+			methods = append(methods, mh.Method)
+		}
+	}
+	if t.AutoHead && node.handler(http.MethodGet) != nil && node.handler(http.MethodHead) == nil {
+		methods = append(methods, http.MethodHead)
+	}
+	sortMethods(methods)
+	return strings.Join(methods, ", ")
+}
+
+// canonicalMethods are the positions of the common methods when sorted by sortMethods
+var canonicalMethods = map[string]int{
+	http.MethodGet:    1,
+	http.MethodHead:   2,
+	http.MethodPost:   3,
+	http.MethodPut:    4,
+	http.MethodPatch:  5,
+	http.MethodDelete: 6,
+}
+
+// sortMethods sorts methods into a canonical order -- GET, HEAD, POST, PUT, PATCH, DELETE, then any others
+// alphabetically -- so that e.g. Allow headers are consistent regardless of the order routes are registered in
+func sortMethods(methods []string) {
+	sort.Slice(methods, func(i, j int) bool {
+		pi, pj := canonicalMethods[methods[i]], canonicalMethods[methods[j]]
+		switch {
+		case pi != 0 && pj != 0:
+			return pi < pj
+		case pi != 0 || pj != 0:
+			return pi != 0
+		}
+		return methods[i] < methods[j]
+	})
 }
 
 type methodHandler struct {
 	Method  string
+	Flag    uint
 	Handler funcs.Handler
+	// Route and Idx are the route as provided to New and its position in the provided slice
+	Route Route
+	Idx   int
+	// raw is the handler before any middleware is applied
+	raw funcs.Handler
+	// format indicates the route's path ends with a format variable, which is split from the last variable
+	format bool
+	// varNames are the names of the route's variables, in order
+	varNames []string
+	// lengths are the length constraints of the route's variables, if it has any
+	lengths []varLength
+	// kinds describe how a typed handler parses the route's variables
+	kinds []funcs.Kind
+	// count is the number of requests dispatched to the handler, if counting is enabled
+	count *atomic.Int64
+	// onParseError responds to path variables which can't be parsed or decoded
+	onParseError funcs.ParseErrorHandler
 }
 
-func (n *node) handler(m string) funcs.Handler {
-	for _, v := range n.hndlrs {
-		if v.Method == m {
-			return v.Handler
+func (n *node) handler(m string) *methodHandler {
+	for i := range n.hndlrs {
+		if n.hndlrs[i].Method == m {
+			return &n.hndlrs[i]
 		}
 	}
 	return nil
 }
 
-func (n *node) setHandler(m string, hndlr funcs.Handler) {
+// handlerFlag is like handler, but looks up the method by its flag, which is cheaper when serving
+func (n *node) handlerFlag(flag uint) *methodHandler {
+	if n.own&flag == 0 {
+		return nil
+	}
+	for i := range n.hndlrs {
+		if n.hndlrs[i].Flag == flag {
+			return &n.hndlrs[i]
+		}
+	}
+	return nil
+}
+
+// takeHandler removes and returns the handler for the method registered at exactly the normalized path, if there is one
+func takeHandler(n *node, path, method string) (methodHandler, bool) {
+	if !strings.HasPrefix(path, n.label) {
+		return methodHandler{}, false
+	}
+	if path = path[len(n.label):]; path != "" {
+		for _, c := range n.children {
+			if mh, ok := takeHandler(c, path, method); ok {
+				return mh, true
+			}
+		}
+		return methodHandler{}, false
+	}
+
+	for j, mh := range n.hndlrs {
+		if mh.Method == method {
+			n.hndlrs = append(n.hndlrs[:j:j], n.hndlrs[j+1:]...)
+			n.own &^= mh.Flag
+			if len(n.hndlrs) == 0 {
+				n.lengths = nil
+			}
+			return mh, true
+		}
+	}
+	return methodHandler{}, false
+}
+
+func (n *node) setHandler(mh methodHandler) {
 	// micro optimization! always resize to exactly fit one more. arguably not worth it.
 	// trades marginally slower init for marginally smaller memory footprint
 	l := len(n.hndlrs)
 	newH := make([]methodHandler, l+1)
 	copy(newH, n.hndlrs)
-	newH[l] = methodHandler{m, hndlr}
+	newH[l] = mh
 	n.hndlrs = newH
+	n.own |= mh.Flag
+	n.lengths = mh.lengths
 }
 
-// Vars reparses the request URI and returns any matched variables and whether or not there was a route matched.
+// Vars reparses the request URI and returns any matched variables and whether or not there was a route matched. The
+// returned slice is allocated on every call; use VarsInto to avoid that.
 func (t *Table) Vars(r *http.Request) ([]string, bool) {
 	var variables funcs.PathVars
-	i, h := t.matchPath(t.acceptMethods(r), r.RequestURI, variables[:])
-	return variables[:i], h != nil
+	i, mh := t.matchVars(r.Method, t.requestPath(r), variables[:])
+	return variables[:i], mh != nil
+}
+
+// VarsInto is like Vars, but the variables are appended to buf[:0], which is returned; if buf has sufficient capacity
+// (at most 8), nothing is allocated.
+func (t *Table) VarsInto(r *http.Request, buf []string) ([]string, bool) {
+	var variables funcs.PathVars
+	i, mh := t.matchVars(r.Method, t.requestPath(r), variables[:])
+	return append(buf[:0], variables[:i]...), mh != nil
+}
+
+// Match describes the route a request was matched to
+type Match struct {
+	// Route is the matched route as it was provided to New
+	Route Route
+	// Index is the position of the matched route within the slice provided to New
+	Index int
+	// Vars are the values of any path variables
+	Vars []string
+	// Names are the names of the path variables, in the same order as Vars
+	Names []string
+}
+
+// Var returns the value of the named path variable and whether or not the route has it
+func (m Match) Var(name string) (string, bool) {
+	for i, n := range m.Names {
+		if n == name && i < len(m.Vars) {
+			return m.Vars[i], true
+		}
+	}
+	return "", false
+}
+
+// Match reparses the request URI and returns the matched route and whether or not there was one. It can be used to
+// correlate requests with the route definitions which handle them.
+func (t *Table) Match(r *http.Request) (Match, bool) {
+	var variables funcs.PathVars
+	i, mh := t.matchVars(r.Method, t.requestPath(r), variables[:])
+	if mh == nil {
+		return Match{}, false
+	}
+	return Match{Route: mh.Route, Index: mh.Idx, Vars: append([]string{}, variables[:i]...), Names: mh.varNames}, true
+}
+
+// matchVars matches the method and path, populating vars, and returns the number of variables and the matched
+// handler, if any
+func (t *Table) matchVars(method, path string, vars []string) (int, *methodHandler) {
+	flag, mask := t.acceptMethods(method)
+	i, node := t.matchPath(mask, path, vars, nil)
+	mh, _ := t.handlerFor(node, method, flag)
+	if mh == nil {
+		// the variables of a partial match are still reported
+		return i, nil
+	}
+	if mh.format {
+		i = splitFormat(vars, i)
+	}
+	return i, mh
 }
 
-func (t *Table) acceptMethods(r *http.Request) uint {
+// handlerFor returns the handler among the node's routes for the method, whose flag is provided, following the
+// precedence documented on MethodAnyFirst, and whether it's a GET handler serving a HEAD request. It returns nil if
+// there's none, or if OptionsHandler should handle the request.
+func (t *Table) handlerFor(n *node, method string, flag uint) (*methodHandler, bool) {
+	if n == nil {
+		return nil, false
+	}
+	if mh := n.handlerFlag(flag); mh != nil {
+		return mh, false
+	}
+	if t.MethodAnyFirst {
+		if mh := n.handlerFlag(t.methodMask); mh != nil {
+			return mh, false
+		}
+	}
+	if method == http.MethodOptions && t.OptionsHandler != nil {
+		return nil, false
+	}
+	if method == http.MethodHead && t.AutoHead {
+		if mh := n.handler(http.MethodGet); mh != nil {
+			return mh, true
+		}
+	}
+	return n.handlerFlag(t.methodMask), false
+}
+
+// requestPath returns the path of the request to be matched against the routing table, i.e. its escaped request URI
+// without the query string
+func (t *Table) requestPath(r *http.Request) string {
+	path := r.RequestURI
+	// a plain loop beats strings.IndexByte on the short paths typical of requests
+	for i := 0; i < len(path); i++ {
+		if path[i] == '?' {
+			path = path[:i]
+			break
+		}
+	}
+	return t.cleanPath(path)
+}
+
+// cleanPath applies the table's configured transformations to a path before matching
+func (t *Table) cleanPath(path string) string {
+	if t.NormalizeUnicode != nil {
+		path = t.NormalizeUnicode(path)
+	}
+	if t.StripMatrixParams {
+		path = stripMatrixParams(path, t.sep)
+	}
+	return path
+}
+
+// stripMatrixParams removes everything from a ';' to the end of its segment
+func stripMatrixParams(path string, sep byte) string {
+	i := strings.IndexByte(path, ';')
+	if i < 0 {
+		return path
+	}
+
+	b := []byte(path[:i])
+	for i < len(path) {
+		// skip the params
+		for i < len(path) && path[i] != sep {
+			i++
+		}
+		// copy the rest of the next segment
+		for ; i < len(path) && path[i] != ';'; i++ {
+			b = append(b, path[i])
+		}
+	}
+	return string(b)
+}
+
+// acceptMethods returns the flag of the method, or 0 if no route has it, and the mask of methods whose handlers may
+// serve a request with it, including those served automatically
+func (t *Table) acceptMethods(method string) (flag, mask uint) {
 	// don't let MethodAny be used as a request method
-	if r.Method == MethodAny {
-		return 0
+	if method == MethodAny {
+		return 0, 0
 	}
 
-	acceptedMethods := t.methodMask
+	flag = t.methodFlagFor(method)
+	mask = flag | t.methodMask
+	if method == http.MethodHead && t.AutoHead {
+		// GET routes may serve HEAD requests, too
+		mask |= t.methodFlagFor(http.MethodGet)
+	}
+	return flag, mask
+}
+
+// methodFlagFor returns the flag of the method, or 0 if no route has it
+func (t *Table) methodFlagFor(method string) uint {
 	for i, m := range t.methods {
-		if m == r.Method {
-			return acceptedMethods | 1<<uint(i)
+		if m == method {
+			return 1 << uint(i)
 		}
 	}
-	return acceptedMethods
+	return 0
 }
 
-func (t *Table) matchPath(methodMask uint, path string, vars []string) (int, *node) {
+// matchPath matches the path against the tree, populating vars, and returns the number of variables and the matched
+// node, if any. The tracer, if not nil, observes the matching.
+//
+// This is the fast path, for the case sensitive routes: at each node, it follows the only child which could match the
+// path. At a node where both a static child and a wildcard child accept the method, the choice between them may
+// require backtracking, so it defers to matchTree, as it does when there's a tracer.
+func (t *Table) matchPath(methodMask uint, path string, vars []string, tr matchTracer) (int, *node) {
+	if tr != nil || len(path) == 0 {
+		return t.matchTree(methodMask, path, vars, tr)
+	}
+
 	var (
 		node            = t.root
+		sep             = t.sep
 		pathIdx, varIdx int
 	)
 	for {
-		// is there a non-nil sub-tree matching this path explicitly with our methods in it?
+		// is there a sub-tree matching this path explicitly with our methods in it?
 		child := node.child(path[pathIdx])
-		if child == nil || (child.methods&methodMask) == 0 {
-			// is there a non-nil sub-tree matching this path via a wildcard with our methods in it?
-			if child = node.child('*'); child == nil || (child.methods&methodMask) == 0 {
-				return varIdx, nil
+		if child != nil && child.methods&methodMask == 0 {
+			child = nil
+		}
+		// is there one matching it via a wildcard?
+		if wild := node.wild; wild != nil && wild != child && wild.methods&methodMask != 0 {
+			if child != nil {
+				return t.matchTree(methodMask, path, vars, nil)
 			}
+			child = wild
+		}
+		if child == nil {
+			return t.matchFolded(methodMask, path, vars, varIdx, nil)
 		}
 
 		lblIdx := 0
 		for {
 			switch {
-			case path[pathIdx] == child.label[lblIdx]:
-				pathIdx++
-				lblIdx++
 			case child.label[lblIdx] == '*':
 				wcStart := pathIdx
-				for pathIdx < len(path) && path[pathIdx] != '/' {
+				for pathIdx < len(path) && path[pathIdx] != sep {
 					pathIdx++
 				}
 				vars[varIdx] = path[wcStart:pathIdx]
 				varIdx++
-				lblIdx++
+			case path[pathIdx] == child.label[lblIdx]:
+				pathIdx++
 			default:
-				return varIdx, nil
+				return t.matchFolded(methodMask, path, vars, varIdx, nil)
 			}
+			lblIdx++
 
-			if pathIdx != len(path) {
-				if lblIdx != len(child.label) {
-					continue
+			if pathIdx == len(path) {
+				// path done
+				if lblIdx != len(child.label) || child.own&methodMask == 0 ||
+					(child.lengths != nil && !fits(child.lengths, vars[:varIdx])) {
+					return t.matchFolded(methodMask, path, vars, varIdx, nil)
 				}
-				node = child
+				return varIdx, child
+			}
+			if lblIdx == len(child.label) {
 				break
 			}
+		}
+		node = child
+	}
+}
+
+// matchTree is the general case of matchPath, which may backtrack; see matchNode
+func (t *Table) matchTree(methodMask uint, path string, vars []string, tr matchTracer) (int, *node) {
+	m := matcher{sep: t.sep, methodMask: methodMask, path: path, tr: tr}
+	i, n := m.matchNode(t.root, 0, vars, 0)
+	if n != nil {
+		return i, n
+	}
+	return t.matchFolded(methodMask, path, vars, i, tr)
+}
+
+// matchFolded matches the path against the case insensitive routes, if there are any, after it didn't match the case
+// sensitive ones; numVars are the variables populated by the failed match, which are reported if there are none
+func (t *Table) matchFolded(methodMask uint, path string, vars []string, numVars int, tr matchTracer) (int, *node) {
+	if t.foldRoot == nil {
+		return numVars, nil
+	}
+	if tr != nil {
+		tr.fold()
+	}
+	m := matcher{sep: t.sep, fold: true, methodMask: methodMask, path: path, tr: tr}
+	return m.matchNode(t.foldRoot, 0, vars, 0)
+}
 
-			// path done
-			if lblIdx != len(child.label) {
+// matcher holds the state of matchTree which is constant as the tree is traversed. If fold is set, the tree's labels
+// are lower case and the path is compared case insensitively.
+type matcher struct {
+	sep        byte
+	fold       bool
+	methodMask uint
+	path       string
+	tr         matchTracer
+}
+
+// matchTracer observes the matcher, e.g. to explain a match or to measure its work in tests; it's nil when serving
+// requests
+type matchTracer interface {
+	// branch is called as the matcher chooses among the children of parent for the path from pathIdx; first is tried
+	// before second, and either or both may be nil
+	branch(parent *node, pathIdx int, first, second *node)
+	// backtrack is called when second, a child of parent, is about to be tried after first, either because first
+	// didn't match or because it matched a route with a lower priority than some of second's
+	backtrack(parent, first, second *node, matched bool)
+	// visit is called as the matcher visits each node, including the roots
+	visit(n *node)
+	// reject is called when n doesn't match: at lblIdx within its label, or at len(n.label) if the path ended at n but
+	// n has no route for the method or the first numVars variables don't satisfy its length constraints
+	reject(n *node, pathIdx, lblIdx, numVars int)
+	// fold is called before the case insensitive routes are tried
+	fold()
+}
+
+// matchNode matches the remainder of the path against n's label and then, iteratively, its descendants. Only at a
+// node with both a static child and a wildcard child accepting the method does it recurse, via matchEither, so that it
+// can backtrack; elsewhere, the only candidate is followed.
+func (m *matcher) matchNode(n *node, pathIdx int, vars []string, varIdx int) (int, *node) {
+	sep, fold, methodMask, path, tr := m.sep, m.fold, m.methodMask, m.path, m.tr
+	for {
+		if tr != nil {
+			tr.visit(n)
+		}
+
+		for lblIdx := 0; lblIdx < len(n.label); lblIdx++ {
+			if pathIdx == len(path) {
+				if tr != nil {
+					tr.reject(n, pathIdx, lblIdx, varIdx)
+				}
 				return varIdx, nil
 			}
 
-			// both done
-			return varIdx, child
+			if n.label[lblIdx] == '*' {
+				wcStart := pathIdx
+				for pathIdx < len(path) && path[pathIdx] != sep {
+					pathIdx++
+				}
+				vars[varIdx] = path[wcStart:pathIdx]
+				varIdx++
+				continue
+			}
+
+			c := path[pathIdx]
+			if fold {
+				c = lowerASCII(c)
+			}
+			if c != n.label[lblIdx] {
+				if tr != nil {
+					tr.reject(n, pathIdx, lblIdx, varIdx)
+				}
+				return varIdx, nil
+			}
+			pathIdx++
 		}
+
+		if pathIdx == len(path) {
+			if n.own&methodMask == 0 || (n.lengths != nil && !fits(n.lengths, vars[:varIdx])) {
+				if tr != nil {
+					tr.reject(n, pathIdx, len(n.label), varIdx)
+				}
+				return varIdx, nil
+			}
+			return varIdx, n
+		}
+
+		next := path[pathIdx]
+		if fold {
+			next = lowerASCII(next)
+		}
+
+		// is there a sub-tree matching this path explicitly with our methods in it?
+		child := n.child(next)
+		if child != nil && child.methods&methodMask == 0 {
+			child = nil
+		}
+		// is there also one matching it via a wildcard?
+		if wild := n.wild; wild != nil && wild != child && wild.methods&methodMask != 0 {
+			if child != nil {
+				return m.matchEither(n, child, wild, pathIdx, vars, varIdx)
+			}
+			child = wild
+		}
+		if tr != nil {
+			tr.branch(n, pathIdx, child, nil)
+		}
+		if child == nil {
+			return varIdx, nil
+		}
+		n = child
+	}
+}
+
+// matchEither matches the remainder of the path against both a static and a wildcard child of n. The one whose
+// subtree has the higher priority is tried first -- the static one if they're equal -- and the other is tried if it
+// doesn't match, or if it matches a route with a lower priority than some in the other's subtree; the match with the
+// higher priority wins.
+func (m *matcher) matchEither(n, static, wild *node, pathIdx int, vars []string, varIdx int) (int, *node) {
+	methodMask, tr := m.methodMask, m.tr
+	first, second := static, wild
+	if wild.priority > static.priority {
+		first, second = wild, static
+	}
+	if tr != nil {
+		tr.branch(n, pathIdx, first, second)
+	}
+
+	i, found := m.matchNode(first, pathIdx, vars, varIdx)
+	if found != nil && found.priorityFor(methodMask) >= second.priority {
+		return i, found
+	}
+	if tr != nil {
+		tr.backtrack(n, first, second, found != nil)
+	}
+	// the second attempt may overwrite the variables of the first match
+	var saved PathVars
+	if found != nil {
+		copy(saved[:], vars[varIdx:])
+	}
+	j, other := m.matchNode(second, pathIdx, vars, varIdx)
+	if found == nil || (other != nil && other.priorityFor(methodMask) > found.priorityFor(methodMask)) {
+		return j, other
+	}
+	copy(vars[varIdx:], saved[:])
+	return i, found
+}
+
+// checks whether any routes along the path are obscured by wildcards, from the root down
+func checkConflicts(root *node, path string) *TableError {
+	n, pathIdx := root, 0
+	for {
+		if err := checkConflict(path[:pathIdx], n); err != nil {
+			return err
+		}
+		if pathIdx == len(path) {
+			return nil
+		}
+		n = n.child(path[pathIdx])
+		pathIdx += len(n.label)
 	}
 }
 
 // checks whether any routes anchored at the current node are obscured by wildcards
-// only matters if methods are the same
+// only matters if methods and priorities of the routes themselves are the same
 func checkConflict(prefix string, n *node) *TableError {
-	wildChild := n.child('*')
+	wildChild := n.wild
 	if len(n.children) < 2 || wildChild == nil {
 		return nil
 	}
 
-	var overlap *node
-	for _, n := range n.children {
-		if n == wildChild {
+	var wild []extracted
+	var wildConflicts, staticConflicts []string
+	for _, c := range n.children {
+		if c == wildChild || c.methods&wildChild.methods == 0 {
 			continue
 		}
-		if wildChild.methods&n.methods > 0 {
-			overlap = n
+		if wild == nil {
+			wild = extract(wildChild)
+		}
+		static := extract(c)
+		conflicting := make([]bool, len(wild))
+		for _, s := range static {
+			overlaps := false
+			for i, w := range wild {
+				if w.method == s.method && w.priority == s.priority {
+					conflicting[i], overlaps = true, true
+				}
+			}
+			if overlaps {
+				staticConflicts = append(staticConflicts, s.format(prefix))
+			}
+		}
+		for i, w := range wild {
+			if conflicting[i] {
+				wildConflicts = append(wildConflicts, w.format(prefix))
+			}
+		}
+		if len(staticConflicts) > 0 {
 			break
 		}
 	}
 
-	if overlap == nil {
-		// both wildcards and static but methods are different
+	if len(staticConflicts) == 0 {
+		// both wildcards and static but methods or priorities are different
 		return nil
 	}
 
-	// we've got a conflict; now gather info for the error message
-
-	staticPrefix := make(map[string][]string)
-	wildPrefix := make(map[string][]string)
-
-	for _, n := range []struct {
-		Map  map[string][]string
-		Node *node
-	}{
-		{wildPrefix, wildChild},
-		{staticPrefix, overlap},
-	} {
-		for _, v := range extract(n.Node) {
-			method := v[len(v)-1]
-			absPath := strings.Join(append([]string{n.Node.label}, v[:len(v)-1]...), "")
-			n.Map[method] = append(n.Map[method], absPath)
-		}
-	}
+	conflicts := append(wildConflicts, staticConflicts...)
+	return &TableError{Type: ErrTypeConflictingRoutes, Msg: "conflicting routes: " + strings.Join(conflicts, ", ")}
+}
 
-	var conflicts []string
-	for method := range staticPrefix {
-		if wildPrefix[method] != nil {
-			for _, s := range append(wildPrefix[method], staticPrefix[method]...) {
-				conflicts = append(conflicts, fmt.Sprintf("\"%v %v%v\"", method, prefix, s))
-			}
-		}
-	}
+// extracted is a route found in a subtree, with its path relative to the subtree's parent
+type extracted struct {
+	path, method string
+	priority     int
+}
 
-	return &TableError{Type: ErrTypeConflictingRoutes, Msg: "conflicting routes: " + strings.Join(conflicts, ", ")}
+func (e extracted) format(prefix string) string {
+	return fmt.Sprintf("\"%v %v%v\"", e.method, prefix, e.path)
 }
 
-// enumerates routes from current node, with method at end:
-// ["/foo", "bar", "GET"]
-func extract(n *node) (sub [][]string) {
+// enumerates routes from current node
+func extract(n *node) (sub []extracted) {
 	for _, c := range n.children {
-		for _, v := range extract(c) {
-			sub = append(sub, append([]string{c.label}, v...))
+		for _, e := range extract(c) {
+			e.path = n.label + e.path
+			sub = append(sub, e)
 		}
 	}
 	for _, h := range n.hndlrs {
-		sub = append(sub, []string{h.Method})
+		sub = append(sub, extracted{path: n.label, method: h.Method, priority: h.Route.Priority})
 	}
 	return
 }
@@ -1,14 +1,18 @@
 package rte_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jwilner/rte"
 	"github.com/jwilner/rte/internal/funcs"
@@ -76,6 +80,54 @@ func TestNew(t *testing.T) {
 			ErrIdx:  0,
 			ErrMsg:  `route 0 "GET /*": invalid segment`,
 		},
+		{
+			Name:    "invalidSegmentVarMidSegment",
+			Routes:  rte.Routes("GET /:a/b:c", func(w http.ResponseWriter, r *http.Request, a, c string) {}),
+			WantErr: true,
+			ErrType: rte.ErrTypeInvalidSegment,
+			ErrIdx:  0,
+			ErrMsg:  `route 0 "GET /:a/b:c": invalid segment`,
+		},
+		{
+			Name:    "invalidSegmentVarWithinVar",
+			Routes:  rte.Routes("GET /:a:b", func(w http.ResponseWriter, r *http.Request, a, b string) {}),
+			WantErr: true,
+			ErrType: rte.ErrTypeInvalidSegment,
+			ErrIdx:  0,
+			ErrMsg:  `route 0 "GET /:a:b": invalid segment`,
+		},
+		{
+			Name:   "consecutive vars",
+			Routes: rte.Routes("GET /:a/:b", func(w http.ResponseWriter, r *http.Request, a, b string) {}),
+		},
+		{
+			Name:    "duplicate var name",
+			Routes:  rte.Routes("GET /:id/:id", func(w http.ResponseWriter, r *http.Request, a, b string) {}),
+			WantErr: true,
+			ErrType: rte.ErrTypeDuplicateVarName,
+			ErrIdx:  0,
+			ErrMsg:  `route 0 "GET /:id/:id": variable name "id" is used more than once`,
+		},
+		{
+			Name:    "duplicate var name separated",
+			Routes:  rte.Routes("GET /:id/sub/:id{4}", func(w http.ResponseWriter, r *http.Request, a, b string) {}),
+			WantErr: true,
+			ErrType: rte.ErrTypeDuplicateVarName,
+			ErrIdx:  0,
+			ErrMsg:  `route 0 "GET /:id/sub/:id{4}": variable name "id" is used more than once`,
+		},
+		{
+			Name:    "duplicate format name",
+			Routes:  rte.Routes("GET /:format/:id.:format", func(w http.ResponseWriter, r *http.Request, a, b, c string) {}),
+			WantErr: true,
+			ErrType: rte.ErrTypeDuplicateVarName,
+			ErrIdx:  0,
+			ErrMsg:  `route 0 "GET /:format/:id.:format": variable name "format" is used more than once`,
+		},
+		{
+			Name:   "unnamed vars",
+			Routes: rte.Routes("GET /:/:", func(w http.ResponseWriter, r *http.Request, a, b string) {}),
+		},
 		{
 			Name: "duplicate handler",
 			Routes: rte.Routes(
@@ -189,6 +241,45 @@ func TestNew(t *testing.T) {
 				"GET /foo/bar", func(http.ResponseWriter, *http.Request) {},
 			),
 		},
+		{
+			Name: "different priorities no conflict",
+			Routes: []rte.Route{
+				{Method: "GET", Path: "/foo/:foo_id", Handler: func(http.ResponseWriter, *http.Request) {}},
+				{Method: "GET", Path: "/foo/bar", Handler: func(http.ResponseWriter, *http.Request) {}, Priority: 1},
+			},
+		},
+		{
+			Name: "same priorities conflict",
+			Routes: []rte.Route{
+				{Method: "GET", Path: "/foo/:foo_id", Handler: func(http.ResponseWriter, *http.Request) {}, Priority: 1},
+				{Method: "GET", Path: "/foo/bar", Handler: func(http.ResponseWriter, *http.Request) {}, Priority: 1},
+			},
+			WantErr: true,
+			ErrIdx:  1,
+			ErrType: rte.ErrTypeConflictingRoutes,
+			ErrMsg:  `route 1 "GET /foo/bar": conflicting routes: "GET /foo/*", "GET /foo/bar"`,
+		},
+		{
+			Name: "invalid name",
+			Routes: []rte.Route{
+				{Name: "get-foo", Method: "GET", Path: "/foo", Handler: func(http.ResponseWriter, *http.Request) {}},
+			},
+			WantErr: true,
+			ErrIdx:  0,
+			ErrType: rte.ErrTypeInvalidName,
+			ErrMsg:  `route 0 "GET /foo": name must be a Go identifier`,
+		},
+		{
+			Name: "duplicate name",
+			Routes: []rte.Route{
+				{Name: "Foo", Method: "GET", Path: "/foo", Handler: func(http.ResponseWriter, *http.Request) {}},
+				{Name: "Foo", Method: "POST", Path: "/foo", Handler: func(http.ResponseWriter, *http.Request) {}},
+			},
+			WantErr: true,
+			ErrIdx:  1,
+			ErrType: rte.ErrTypeDuplicateName,
+			ErrMsg:  `route 1 "POST /foo": name "Foo" is already in use`,
+		},
 	} {
 		t.Run(c.Name, func(t *testing.T) {
 			defer func() {
@@ -399,6 +490,34 @@ func TestMatching(t *testing.T) {
 			),
 			code: 404, body: "404",
 		},
+		{
+			name: "query-ignored",
+			req:  httptest.NewRequest("GET", "/abc?x=y", nil),
+			rte:  rte.Routes("GET /abc", h200),
+			code: 200, body: "null",
+		},
+		{
+			name: "query-wildcard",
+			req:  httptest.NewRequest("GET", "/files/1?x=y", nil),
+			rte: rte.Routes(
+				"GET /files/:id",
+				func(w http.ResponseWriter, r *http.Request, id string) {
+					_ = json.NewEncoder(w).Encode([]string{id})
+				},
+			),
+			code: 200, body: `["1"]`,
+		},
+		{
+			name: "empty-query-wildcard",
+			req:  httptest.NewRequest("GET", "/files/1?", nil),
+			rte: rte.Routes(
+				"GET /files/:id",
+				func(w http.ResponseWriter, r *http.Request, id string) {
+					_ = json.NewEncoder(w).Encode([]string{id})
+				},
+			),
+			code: 200, body: `["1"]`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -556,3 +675,1362 @@ func TestParseVars(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeUnicode(t *testing.T) {
+	// a stand-in for a real normalizer like golang.org/x/text/unicode/norm.NFC.String
+	toNFC := func(path string) string {
+		return strings.ReplaceAll(path, "e\u0301", "\u00e9")
+	}
+
+	tbl := rte.Must(rte.Routes(
+		"GET /caf\u00e9/:name", func(w http.ResponseWriter, r *http.Request, name string) {
+			_, _ = fmt.Fprint(w, name)
+		},
+	))
+
+	for _, c := range []struct {
+		name, path string
+		normalize  func(string) string
+		wantCode   int
+	}{
+		{"nfc", "/caf\u00e9/bob", nil, 200},
+		{"nfd disabled", "/cafe\u0301/bob", nil, 404},
+		{"nfd", "/cafe\u0301/bob", toNFC, 200},
+		{"trailing dots disabled", "/cafe\u0301./bob", toNFC, 404},
+		{"trailing dots", "/cafe\u0301./bob", func(p string) string { return rte.TrimTrailingDots(toNFC(p), '/') }, 200},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tbl.NormalizeUnicode = c.normalize
+
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+
+			if w.Code != c.wantCode {
+				t.Fatalf("want %v but got %v", c.wantCode, w.Code)
+			}
+			if c.wantCode == 200 && w.Body.String() != "bob" {
+				t.Fatalf("want %q but got %q", "bob", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCheckConflicts(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+
+	t.Run("clean", func(t *testing.T) {
+		users := rte.Routes(
+			"GET /users/:id", h,
+			"POST /users", h,
+		)
+		billing := rte.Routes(
+			"GET /billing/:id", h,
+			"PUT /users/:id", h,
+		)
+		if errs := rte.CheckConflicts(append(users, billing...)); len(errs) != 0 {
+			t.Fatalf("expected no errors but got %v", errs)
+		}
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		errs := rte.CheckConflicts(rte.Routes(
+			"GET /users/:id", h,
+			"GET /users/me", h,
+			"GET /billing", h,
+			"GET /users/:id", h,
+			"GET /users/you", h,
+			"POST /users/:id", h,
+			"GET /billing", h,
+		))
+
+		var got []string
+		for _, err := range errs {
+			got = append(got, err.Error())
+		}
+		want := []string{
+			`route 1 "GET /users/me": conflicting routes: "GET /users/*", "GET /users/me"`,
+			`route 3 "GET /users/:id": duplicate handler`,
+			`route 4 "GET /users/you": conflicting routes: "GET /users/*", "GET /users/you"`,
+			`route 6 "GET /billing": duplicate handler`,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("want %q but got %q", want, got)
+		}
+		for _, err := range errs {
+			if _, ok := err.(*rte.TableError); !ok {
+				t.Fatalf("expected a *rte.TableError but got %T", err)
+			}
+		}
+	})
+}
+
+func TestPriority(t *testing.T) {
+	me := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "me")
+	}
+	id := func(w http.ResponseWriter, r *http.Request, id string) {
+		_, _ = fmt.Fprintf(w, "id %v", id)
+	}
+	settings := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "settings")
+	}
+
+	for _, c := range []struct {
+		name   string
+		routes []rte.Route
+		path   string
+		want   string
+		err    string
+	}{
+		{
+			name: "static preferred",
+			routes: []rte.Route{
+				{Method: "GET", Path: "/users/me", Handler: me, Priority: 1},
+				{Method: "GET", Path: "/users/:id", Handler: id},
+			},
+			path: "/users/me",
+			want: "me",
+		},
+		{
+			name: "static preferred falls back",
+			routes: []rte.Route{
+				{Method: "GET", Path: "/users/me", Handler: me, Priority: 1},
+				{Method: "GET", Path: "/users/:id", Handler: id},
+			},
+			path: "/users/mine",
+			want: "id mine",
+		},
+		{
+			name: "wildcard preferred",
+			routes: []rte.Route{
+				{Method: "GET", Path: "/users/me", Handler: me},
+				{Method: "GET", Path: "/users/:id", Handler: id, Priority: 1},
+			},
+			path: "/users/me",
+			want: "id me",
+		},
+		{
+			name: "negative priority",
+			routes: []rte.Route{
+				{Method: "GET", Path: "/users/me", Handler: me, Priority: -1},
+				{Method: "GET", Path: "/users/:id", Handler: id},
+			},
+			path: "/users/me",
+			want: "id me",
+		},
+		{
+			name: "wildcard preferred falls back",
+			routes: []rte.Route{
+				{Method: "GET", Path: "/users/me/settings", Handler: settings},
+				{Method: "GET", Path: "/users/:id", Handler: id, Priority: 1},
+			},
+			path: "/users/me/settings",
+			want: "settings",
+		},
+		{
+			name: "subtree priority doesn't settle a conflict",
+			routes: []rte.Route{
+				{Method: "GET", Path: "/users/me", Handler: me},
+				{Method: "GET", Path: "/users/meow", Handler: me, Priority: 1},
+				{Method: "GET", Path: "/users/:id", Handler: id},
+			},
+			err: `route 2 "GET /users/:id": conflicting routes: "GET /users/*", "GET /users/me"`,
+		},
+		{
+			name: "conflict below a static prefix",
+			routes: []rte.Route{
+				{Method: "GET", Path: "/users/me", Handler: me},
+				{Method: "GET", Path: "/users/:id", Handler: id, Priority: 1},
+				{Method: "GET", Path: "/users/meow", Handler: me, Priority: 1},
+			},
+			err: `route 2 "GET /users/meow": conflicting routes: "GET /users/*", "GET /users/meow"`,
+		},
+		{
+			name: "mixed priorities",
+			routes: []rte.Route{
+				{Method: "GET", Path: "/users/me", Handler: me},
+				{Method: "GET", Path: "/users/meow", Handler: settings, Priority: 2},
+				{Method: "GET", Path: "/users/:id", Handler: id, Priority: 1},
+			},
+			path: "/users/me",
+			want: "id me",
+		},
+		{
+			name: "mixed priorities static wins",
+			routes: []rte.Route{
+				{Method: "GET", Path: "/users/me", Handler: me},
+				{Method: "GET", Path: "/users/meow", Handler: settings, Priority: 2},
+				{Method: "GET", Path: "/users/:id", Handler: id, Priority: 1},
+			},
+			path: "/users/meow",
+			want: "settings",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tbl, err := rte.New(c.routes)
+			if c.err != "" {
+				if err == nil || err.Error() != c.err {
+					t.Fatalf("want error %q but got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+
+			if w.Code != 200 || w.Body.String() != c.want {
+				t.Fatalf("want 200 %q but got %v %q", c.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestStaticSiblingOfWildcard(t *testing.T) {
+	static := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "static")
+	}
+	inbox := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "inbox")
+	}
+	wild := func(w http.ResponseWriter, r *http.Request, name string) {
+		_, _ = fmt.Fprintf(w, "wildcard %v", name)
+	}
+
+	// static routes don't implicitly take precedence over their wildcard siblings
+	_, err := rte.New([]rte.Route{
+		{Method: "GET", Path: "/files/index", Handler: static},
+		{Method: "GET", Path: "/files/:name", Handler: wild},
+	})
+	if tErr, ok := err.(*rte.TableError); !ok || tErr.Type != rte.ErrTypeConflictingRoutes {
+		t.Fatalf("expected a conflicting routes error but got %v", err)
+	}
+
+	for _, order := range []struct {
+		name   string
+		routes []rte.Route
+	}{
+		{"static first", []rte.Route{
+			{Method: "GET", Path: "/files/index", Handler: static, Priority: 1},
+			{Method: "GET", Path: "/files/inbox", Handler: inbox, Priority: 1},
+			{Method: "GET", Path: "/files/:name", Handler: wild},
+		}},
+		{"wildcard first", []rte.Route{
+			{Method: "GET", Path: "/files/:name", Handler: wild},
+			{Method: "GET", Path: "/files/index", Handler: static, Priority: 1},
+			{Method: "GET", Path: "/files/inbox", Handler: inbox, Priority: 1},
+		}},
+	} {
+		tbl := rte.Must(order.routes)
+
+		for _, c := range []struct {
+			path, want string
+		}{
+			{"/files/index", "static"},
+			{"/files/other", "wildcard other"},
+			{"/files/inbox", "inbox"},
+			// share a prefix with the static labels, which are split at "in", so the matcher must back out of them
+			{"/files/in", "wildcard in"},
+			{"/files/inde", "wildcard inde"},
+			{"/files/indexes", "wildcard indexes"},
+			{"/files/inb", "wildcard inb"},
+		} {
+			t.Run(order.name+" "+c.path, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+				if w.Code != 200 || w.Body.String() != c.want {
+					t.Fatalf("want 200 %q but got %v %q", c.want, w.Code, w.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	routes := rte.Routes(
+		"GET /foo", h,
+		"POST /foo", h,
+		"GET /foo/:foo_id", h,
+		"~ /bar/:bar_id", h,
+	)
+	tbl := rte.Must(routes)
+
+	for _, c := range []struct {
+		method, path string
+		wantIdx      int
+		wantVars     []string
+		wantOK       bool
+	}{
+		{"GET", "/foo", 0, []string{}, true},
+		{"POST", "/foo", 1, []string{}, true},
+		{"GET", "/foo/abc", 2, []string{"abc"}, true},
+		{"DELETE", "/bar/abc", 3, []string{"abc"}, true},
+		{"DELETE", "/foo", 0, nil, false},
+		{"GET", "/baz", 0, nil, false},
+	} {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			m, ok := tbl.Match(httptest.NewRequest(c.method, c.path, nil))
+			if ok != c.wantOK {
+				t.Fatalf("want ok %v but got %v", c.wantOK, ok)
+			}
+			if !ok {
+				return
+			}
+			if m.Index != c.wantIdx {
+				t.Fatalf("want index %v but got %v", c.wantIdx, m.Index)
+			}
+			if m.Route.String() != routes[c.wantIdx].String() {
+				t.Fatalf("want route %v but got %v", routes[c.wantIdx], m.Route)
+			}
+			if !reflect.DeepEqual(m.Vars, c.wantVars) {
+				t.Fatalf("want vars %#v but got %#v", c.wantVars, m.Vars)
+			}
+		})
+	}
+}
+
+func TestWithSeparator(t *testing.T) {
+	tbl := rte.Must(rte.Routes(
+		"GET .users", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "users")
+		},
+		"GET .users.:user_id", func(w http.ResponseWriter, r *http.Request, userID string) {
+			_, _ = fmt.Fprintf(w, "user %v", userID)
+		},
+		"GET .users.:user_id.posts.:post_id", func(w http.ResponseWriter, r *http.Request, userID, postID string) {
+			_, _ = fmt.Fprintf(w, "user %v post %v", userID, postID)
+		},
+	), rte.WithSeparator('.'))
+
+	for _, c := range []struct {
+		path, want string
+		code       int
+	}{
+		{".users", "users", 200},
+		{".users.abc", "user abc", 200},
+		{".users.a/b", "user a/b", 200},
+		{".users.abc.posts.123", "user abc post 123", 200},
+		{".users.abc.def", "404 page not found\n", 404},
+		{"/users", "404 page not found\n", 404},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, &http.Request{Method: "GET", RequestURI: c.path})
+			if w.Code != c.code || w.Body.String() != c.want {
+				t.Fatalf("want %v %q but got %v %q", c.code, c.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	for _, c := range []struct {
+		name, path string
+		typ        int
+	}{
+		{"no initial separator", "/users", rte.ErrTypeNoInitialSlash},
+		{"var mid segment", ".users.abc:user_id", rte.ErrTypeInvalidSegment},
+		{"slash is not separator", ".users/:user_id", rte.ErrTypeInvalidSegment},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := rte.New([]rte.Route{
+				{Method: "GET", Path: c.path, Handler: func(http.ResponseWriter, *http.Request, string) {}},
+			}, rte.WithSeparator('.'))
+			if err == nil || err.(*rte.TableError).Type != c.typ {
+				t.Fatalf("want error type %v but got %v", c.typ, err)
+			}
+		})
+	}
+}
+
+func TestWithConverter(t *testing.T) {
+	type intHandler func(w http.ResponseWriter, id int)
+	type sliceHandler func(w http.ResponseWriter, vars []string)
+
+	intConverter := func(i interface{}) (rte.VarsHandler, int, bool) {
+		h, ok := i.(intHandler)
+		if !ok {
+			return nil, 0, false
+		}
+		return func(w http.ResponseWriter, r *http.Request, vars rte.PathVars) {
+			id, err := strconv.Atoi(vars[0])
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			h(w, id)
+		}, 1, true
+	}
+	sliceConverter := func(i interface{}) (rte.VarsHandler, int, bool) {
+		h, ok := i.(sliceHandler)
+		if !ok {
+			return nil, 0, false
+		}
+		return func(w http.ResponseWriter, r *http.Request, vars rte.PathVars) {
+			h(w, vars[:2])
+		}, 2, true
+	}
+
+	intRoute := rte.Route{Method: "GET", Path: "/int/:id", Handler: intHandler(func(w http.ResponseWriter, id int) {
+		_, _ = fmt.Fprintf(w, "%d", id+1)
+	})}
+	sliceRoute := rte.Route{Method: "GET", Path: "/slice/:a/:b", Handler: sliceHandler(func(w http.ResponseWriter, vars []string) {
+		_, _ = fmt.Fprintf(w, "%v", vars)
+	})}
+
+	intTbl := rte.Must([]rte.Route{intRoute}, rte.WithConverter(intConverter))
+	sliceTbl := rte.Must([]rte.Route{sliceRoute}, rte.WithConverter(sliceConverter))
+
+	for _, c := range []struct {
+		name       string
+		tbl        *rte.Table
+		path, want string
+	}{
+		{"int", intTbl, "/int/41", "42"},
+		{"slice", sliceTbl, "/slice/a/b", "[a b]"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c.tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if w.Code != 200 || w.Body.String() != c.want {
+				t.Fatalf("want 200 %q but got %v %q", c.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	for _, c := range []struct {
+		name  string
+		route rte.Route
+		opts  []rte.Option
+	}{
+		{"no converter", intRoute, nil},
+		{"other converter", intRoute, []rte.Option{rte.WithConverter(sliceConverter)}},
+		{"other converter reversed", sliceRoute, []rte.Option{rte.WithConverter(intConverter)}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := rte.New([]rte.Route{c.route}, c.opts...)
+			if err == nil || err.(*rte.TableError).Type != rte.ErrTypeConversionFailure {
+				t.Fatalf("want conversion failure but got %v", err)
+			}
+		})
+	}
+}
+
+func TestOptionsHandler(t *testing.T) {
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, body)
+		}
+	}
+
+	tbl := rte.Must(rte.Routes(
+		"GET /foo", respond("get foo"),
+		"OPTIONS /bar", respond("options bar"),
+		rte.MethodAny+" /baz", respond("any baz"),
+	))
+	tbl.OptionsHandler = respond("options")
+
+	for _, c := range []struct {
+		method, path, want string
+	}{
+		{"OPTIONS", "/some/arbitrary/path", "options"},
+		{"OPTIONS", "/foo", "options"},
+		{"OPTIONS", "/bar", "options bar"},
+		{"OPTIONS", "/baz", "options"},
+		{"GET", "/foo", "get foo"},
+		{"POST", "/baz", "any baz"},
+		{"GET", "/some/arbitrary/path", "404 page not found\n"},
+	} {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+			if w.Body.String() != c.want {
+				t.Fatalf("want %q but got %q", c.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAutoMethods(t *testing.T) {
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Handler", body)
+			_, _ = fmt.Fprint(w, body)
+		}
+	}
+
+	newTable := func(anyFirst bool) *rte.Table {
+		tbl := rte.Must(rte.Routes(
+			"GET /foo", respond("get foo"),
+			"HEAD /bar", respond("head bar"),
+			"GET /bar", respond("get bar"),
+			rte.MethodAny+" /foo", respond("any foo"),
+			rte.MethodAny+" /baz", respond("any baz"),
+		))
+		tbl.OptionsHandler = respond("options")
+		tbl.AutoHead = true
+		tbl.MethodAnyFirst = anyFirst
+		return tbl
+	}
+
+	for _, c := range []struct {
+		name              string
+		anyFirst          bool
+		method, path      string
+		handler, wantBody string
+	}{
+		{"default options on any", false, "OPTIONS", "/foo", "options", "options"},
+		{"default head on any", false, "HEAD", "/foo", "get foo", ""},
+		{"default explicit head", false, "HEAD", "/bar", "head bar", "head bar"},
+		{"default head without get", false, "HEAD", "/baz", "any baz", "any baz"},
+		{"default options unmatched", false, "OPTIONS", "/qux", "options", "options"},
+		{"default other method", false, "POST", "/foo", "any foo", "any foo"},
+		{"any first options on any", true, "OPTIONS", "/foo", "any foo", "any foo"},
+		{"any first head on any", true, "HEAD", "/foo", "any foo", "any foo"},
+		{"any first explicit head", true, "HEAD", "/bar", "head bar", "head bar"},
+		{"any first head without get", true, "HEAD", "/baz", "any baz", "any baz"},
+		{"any first options unmatched", true, "OPTIONS", "/qux", "options", "options"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tbl := newTable(c.anyFirst)
+			r := httptest.NewRequest(c.method, c.path, nil)
+
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, r)
+			if got := w.Header().Get("X-Handler"); got != c.handler {
+				t.Fatalf("want handler %q but got %q", c.handler, got)
+			}
+			if w.Body.String() != c.wantBody {
+				t.Fatalf("want body %q but got %q", c.wantBody, w.Body.String())
+			}
+
+			// OptionsHandler isn't a route
+			if _, ok := tbl.Match(r); ok != (c.handler != "options") {
+				t.Fatalf("want Match %v but got %v", !ok, ok)
+			}
+		})
+	}
+
+	t.Run("auto head inspection", func(t *testing.T) {
+		tbl := rte.Must([]rte.Route{{Method: "GET", Path: "/s/:id", Doc: "an s", Handler: respond("get s")}})
+		tbl.AutoHead = true
+
+		r := httptest.NewRequest("HEAD", "/s/1", nil)
+		if m, ok := tbl.Match(r); !ok || m.Route.Path != "/s/:id" {
+			t.Fatalf("want a match for /s/:id but got %v %v", m, ok)
+		}
+		if vars, ok := tbl.Vars(r); !ok || !reflect.DeepEqual(vars, []string{"1"}) {
+			t.Fatalf("want vars [1] but got %v %v", vars, ok)
+		}
+		if err := tbl.ValidatePath("HEAD", "/s/1"); err != nil {
+			t.Fatalf("want valid path but got %v", err)
+		}
+		if doc := tbl.Doc("HEAD", "/s/1"); doc != "an s" {
+			t.Fatalf("want doc %q but got %q", "an s", doc)
+		}
+
+		tbl.AutoHead = false
+		if _, ok := tbl.Match(r); ok {
+			t.Fatal("want no match without AutoHead")
+		}
+	})
+
+	t.Run("auto head without any", func(t *testing.T) {
+		tbl := rte.Must(rte.Routes("GET /foo", respond("get foo"), "POST /bar", respond("post bar")))
+		tbl.AutoHead = true
+		tbl.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		})
+
+		w := httptest.NewRecorder()
+		tbl.ServeHTTP(w, httptest.NewRequest("HEAD", "/foo", nil))
+		if got := w.Header().Get("X-Handler"); got != "get foo" || w.Body.Len() != 0 {
+			t.Fatalf("want handler %q and no body but got %q and %q", "get foo", got, w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		tbl.ServeHTTP(w, httptest.NewRequest("DELETE", "/foo", nil))
+		if got := w.Header().Get("Allow"); got != "GET, HEAD" {
+			t.Fatalf("want Allow %q but got %q", "GET, HEAD", got)
+		}
+
+		w = httptest.NewRecorder()
+		tbl.ServeHTTP(w, httptest.NewRequest("HEAD", "/bar", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("want %v but got %v", http.StatusMethodNotAllowed, w.Code)
+		}
+	})
+}
+
+func TestVarsInto(t *testing.T) {
+	tbl := rte.Must(rte.Routes("GET /:abc/abc/:def", func(http.ResponseWriter, *http.Request) {}))
+
+	buf := make([]string, 1, 8)
+	res, ok := tbl.VarsInto(httptest.NewRequest("GET", "/blah/abc/bar", nil), buf)
+	if !ok || !reflect.DeepEqual(res, []string{"blah", "bar"}) {
+		t.Fatalf("Expected [blah bar] but got %#v %v", res, ok)
+	}
+	if &res[0] != &buf[:1][0] {
+		t.Fatal("Expected the provided buffer to be used")
+	}
+
+	res, ok = tbl.VarsInto(httptest.NewRequest("GET", "/other", nil), res)
+	if ok || !reflect.DeepEqual(res, []string{"other"}) {
+		t.Fatalf("Expected partial match [other] but got %#v %v", res, ok)
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	h := func(http.ResponseWriter, *http.Request) {}
+	var routes []rte.Route
+	for i := 0; i < 1000; i++ {
+		routes = append(routes,
+			rte.Route{Method: "GET", Path: fmt.Sprintf("/service%d/users/:user_id/posts/:post_id", i), Handler: h},
+			rte.Route{Method: "POST", Path: fmt.Sprintf("/service%d/users/:user_id{4,16}/posts", i), Handler: h},
+			rte.Route{Method: "GET", Path: fmt.Sprintf("/service%d/static/health", i), Handler: h},
+		)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = rte.Must(routes)
+	}
+}
+
+func BenchmarkVars(b *testing.B) {
+	tbl := rte.Must(rte.Routes("GET /:abc/abc/:def", func(http.ResponseWriter, *http.Request) {}))
+	r := httptest.NewRequest("GET", "/blah/abc/bar", nil)
+
+	b.Run("Vars", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = tbl.Vars(r)
+		}
+	})
+	b.Run("VarsInto", func(b *testing.B) {
+		buf := make([]string, 0, 8)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf, _ = tbl.VarsInto(r, buf)
+		}
+	})
+}
+
+func TestRequireResponse(t *testing.T) {
+	routes := rte.Routes(
+		"GET /nothing", func(w http.ResponseWriter, r *http.Request) {},
+		"GET /header", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"GET /body", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "body")
+		},
+	)
+
+	for _, c := range []struct {
+		name     string
+		require  bool
+		status   int
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"disabled", false, 0, "/nothing", 200, ""},
+		{"nothing", true, 0, "/nothing", 500, ""},
+		{"nothing configured", true, http.StatusBadGateway, "/nothing", 502, ""},
+		{"header", true, 0, "/header", 204, ""},
+		{"body", true, 0, "/body", 200, "body"},
+		{"default", true, 0, "/missing", 404, "404 page not found\n"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tbl := rte.Must(routes)
+			tbl.RequireResponse = c.require
+			tbl.MissingResponseStatus = c.status
+
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if w.Code != c.wantCode || w.Body.String() != c.wantBody {
+				t.Fatalf("want %v %q but got %v %q", c.wantCode, c.wantBody, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("informational", func(t *testing.T) {
+		tbl := rte.Must(rte.Routes("GET /hints", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", "</style.css>; rel=preload")
+			w.WriteHeader(http.StatusEarlyHints)
+		}))
+		tbl.RequireResponse = true
+
+		// a recorder reports an informational status as the response's, so serve it for real
+		srv := httptest.NewServer(tbl)
+		defer srv.Close()
+
+		resp, err := srv.Client().Get(srv.URL + "/hints")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != 500 {
+			t.Fatalf("want 500 but got %v", resp.StatusCode)
+		}
+	})
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, body)
+		}
+	}
+
+	tbl := rte.Must([]rte.Route{
+		{Method: "GET", Path: "/content/page", Handler: respond("page"), CaseInsensitive: true},
+		{
+			Method: "GET", Path: "/content/page/:slug", CaseInsensitive: true,
+			Handler: func(w http.ResponseWriter, r *http.Request, slug string) {
+				_, _ = fmt.Fprintf(w, "slug %v", slug)
+			},
+		},
+		{Method: "GET", Path: "/api/users", Handler: respond("users")},
+		{Method: "GET", Path: "/About", Handler: respond("exact about")},
+		{Method: "GET", Path: "/about", Handler: respond("any about"), CaseInsensitive: true},
+	})
+
+	for _, c := range []struct {
+		path, want string
+	}{
+		{"/content/page", "page"},
+		{"/Content/Page", "page"},
+		{"/CONTENT/PAGE/Hello-World", "slug Hello-World"},
+		{"/api/users", "users"},
+		{"/API/users", "404 page not found\n"},
+		{"/api/Users", "404 page not found\n"},
+		{"/About", "exact about"},
+		{"/about", "any about"},
+		{"/ABOUT", "any about"},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if w.Body.String() != c.want {
+				t.Fatalf("want %q but got %q", c.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestStripMatrixParams(t *testing.T) {
+	tbl := rte.Must(rte.Routes(
+		"GET /users/:id", func(w http.ResponseWriter, r *http.Request, id string) {
+			_, _ = fmt.Fprintf(w, "user %v", id)
+		},
+		"GET /users/:id/posts", func(w http.ResponseWriter, r *http.Request, id string) {
+			_, _ = fmt.Fprintf(w, "posts %v", id)
+		},
+	))
+	tbl.StripMatrixParams = true
+
+	for _, c := range []struct {
+		path, want string
+	}{
+		{"/users/42", "user 42"},
+		{"/users/42;jsessionid=abc", "user 42"},
+		{"/users;a=b/42;c=d;e=f", "user 42"},
+		{"/users/42;jsessionid=abc/posts", "posts 42"},
+		{"/users/42;jsessionid=abc/posts;x=y", "posts 42"},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if w.Body.String() != c.want {
+				t.Fatalf("want %q but got %q", c.want, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		tbl.StripMatrixParams = false
+		w := httptest.NewRecorder()
+		tbl.ServeHTTP(w, httptest.NewRequest("GET", "/users/42;jsessionid=abc", nil))
+		if want := "user 42;jsessionid=abc"; w.Body.String() != want {
+			t.Fatalf("want %q but got %q", want, w.Body.String())
+		}
+	})
+}
+
+func TestObserve(t *testing.T) {
+	tbl := rte.Must(rte.Routes(
+		"GET /ok", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "ok")
+		},
+		"POST /created", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.WriteHeader(http.StatusTeapot) // superfluous; ignored
+		},
+		"GET /silent", func(w http.ResponseWriter, r *http.Request) {},
+		"GET /missing-response", func(w http.ResponseWriter, r *http.Request) {},
+	))
+
+	type observation struct {
+		method, path string
+		status       int
+	}
+	var observed []observation
+	for i := 0; i < 2; i++ {
+		tbl.Observe(func(r *http.Request, status int, duration time.Duration) {
+			if duration < 0 {
+				t.Errorf("negative duration %v", duration)
+			}
+			observed = append(observed, observation{r.Method, r.URL.Path, status})
+		})
+	}
+
+	for _, c := range []struct {
+		method, path string
+		require      bool
+		want         int
+	}{
+		{"GET", "/ok", false, 200},
+		{"POST", "/created", false, 201},
+		{"GET", "/silent", false, 200},
+		{"GET", "/missing", false, 404},
+		{"GET", "/missing-response", true, 500},
+	} {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			observed = nil
+			tbl.RequireResponse = c.require
+
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+
+			want := observation{c.method, c.path, c.want}
+			if !reflect.DeepEqual(observed, []observation{want, want}) {
+				t.Fatalf("want two of %v but got %v", want, observed)
+			}
+			if w.Code != c.want {
+				t.Fatalf("want response %v but got %v", c.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestObserveStreaming(t *testing.T) {
+	tbl := rte.Must(rte.Routes(
+		"GET /events", func(w http.ResponseWriter, r *http.Request) {
+			f, ok := w.(http.Flusher)
+			if !ok {
+				t.Error("want an http.Flusher")
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprint(w, "data: 1\n\n")
+			f.Flush()
+		},
+		"GET /socket", func(w http.ResponseWriter, r *http.Request) {
+			h, ok := w.(http.Hijacker)
+			if !ok {
+				t.Error("want an http.Hijacker")
+				return
+			}
+			conn, rw, err := h.Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer func() { _ = conn.Close() }()
+			_, _ = rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+			_ = rw.Flush()
+		},
+	))
+	tbl.RequireResponse = true
+	tbl.AutoHead = true
+
+	// observers run after the response may have reached the client
+	statuses := make(chan int, 1)
+	tbl.Observe(func(r *http.Request, status int, duration time.Duration) {
+		statuses <- status
+	})
+
+	srv := httptest.NewServer(tbl)
+	defer srv.Close()
+
+	for _, c := range []struct {
+		method, path, want string
+	}{
+		{"GET", "/events", "data: 1\n\n"},
+		{"HEAD", "/events", ""},
+		{"GET", "/socket", "hijacked"},
+	} {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			req, err := http.NewRequest(c.method, srv.URL+c.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != 200 || string(body) != c.want {
+				t.Fatalf("want 200 %q but got %v %q", c.want, resp.StatusCode, body)
+			}
+			if status := <-statuses; status != 200 {
+				t.Fatalf("want observed 200 but got %v", status)
+			}
+		})
+	}
+}
+
+func TestDefaultByPrefix(t *testing.T) {
+	respond := func(contentType, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, body)
+		}
+	}
+
+	tbl := rte.Must(rte.Routes(
+		"GET /api/users", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "users")
+		},
+	))
+	tbl.DefaultByPrefix = []rte.PrefixHandler{
+		{Prefix: "/", Handler: respond("text/html", "<h1>Not Found</h1>")},
+		{Prefix: "/api/", Handler: respond("application/json", `{"error":"not found"}`)},
+		{Prefix: "/api/v2/", Handler: respond("application/json", `{"errors":["not found"]}`)},
+		{Prefix: "/api/", Handler: respond("text/plain", "shadowed")},
+	}
+
+	for _, c := range []struct {
+		path, wantType, wantBody string
+	}{
+		{"/api/missing", "application/json", `{"error":"not found"}`},
+		{"/api/v2/missing", "application/json", `{"errors":["not found"]}`},
+		{"/missing", "text/html", "<h1>Not Found</h1>"},
+		{"/api/users", "text/plain; charset=utf-8", "users"},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if got := w.Header().Get("Content-Type"); got != c.wantType || w.Body.String() != c.wantBody {
+				t.Fatalf("want %q %q but got %q %q", c.wantType, c.wantBody, got, w.Body.String())
+			}
+		})
+	}
+
+	tbl.DefaultByPrefix = []rte.PrefixHandler{{Prefix: "/api/", Handler: respond("application/json", "{}")}}
+	w := httptest.NewRecorder()
+	tbl.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Body.String() != "404 page not found\n" {
+		t.Fatalf("want fallback to Default but got %q", w.Body.String())
+	}
+}
+
+func TestDuplicateResolver(t *testing.T) {
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, body)
+		}
+	}
+	routes := []rte.Route{
+		{Method: "GET", Path: "/foo/:id", Handler: respond("first"), Name: "First"},
+		{Method: "POST", Path: "/foo/:id", Handler: respond("post")},
+		{Method: "GET", Path: "/foo/:foo_id", Handler: respond("second"), Name: "Second"},
+	}
+
+	for _, c := range []struct {
+		name     string
+		resolve  func(existing, new rte.Route) rte.Route
+		wantBody string
+		wantName string
+	}{
+		{"last wins", func(existing, new rte.Route) rte.Route { return new }, "second", "Second"},
+		{"first wins", func(existing, new rte.Route) rte.Route { return existing }, "first", "First"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tbl, err := rte.New(routes, rte.WithDuplicateResolver(c.resolve))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, r := range []struct{ method, want string }{{"GET", c.wantBody}, {"POST", "post"}} {
+				w := httptest.NewRecorder()
+				tbl.ServeHTTP(w, httptest.NewRequest(r.method, "/foo/1", nil))
+				if w.Body.String() != r.want {
+					t.Fatalf("%v: want %q but got %q", r.method, r.want, w.Body.String())
+				}
+			}
+
+			if m, ok := tbl.Match(httptest.NewRequest("GET", "/foo/1", nil)); !ok || m.Route.Name != c.wantName || m.Index != 2 {
+				t.Fatalf("want %q at 2 but got %q at %v", c.wantName, m.Route.Name, m.Index)
+			}
+		})
+	}
+
+	if _, err := rte.New(routes); err == nil || err.(*rte.TableError).Type != rte.ErrTypeDuplicateHandler {
+		t.Fatalf("want a duplicate handler error without a resolver but got %v", err)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	tbl := rte.Must(rte.Routes(
+		"GET /foo", h,
+		"GET /bar/:id", h,
+		"PUT /bar/:id", h,
+		rte.MethodAny+" /baz", h,
+		"PROPFIND /qux", h,
+		"DELETE /qux", h,
+		"OPTIONS /qux", h,
+		"PATCH /qux", h,
+		"HEAD /qux", h,
+		"POST /qux", h,
+		"GET /qux", h,
+		"LOCK /qux", h,
+	))
+
+	for _, c := range []struct {
+		name, method, path string
+		notAllowed         bool
+		wantCode           int
+		wantAllow          string
+	}{
+		{"disabled", "DELETE", "/foo", false, 404, ""},
+		{"only GET", "DELETE", "/foo", true, 405, "GET"},
+		{"unknown path", "DELETE", "/unknown", true, 404, ""},
+		{"unknown path known method", "GET", "/unknown", true, 404, ""},
+		{"wildcard", "POST", "/bar/123", true, 405, "GET, PUT"},
+		{"canonical order", "PUT", "/qux", true, 405, "GET, HEAD, POST, PATCH, DELETE, LOCK, OPTIONS, PROPFIND"},
+		{"allowed", "PUT", "/bar/123", true, 200, ""},
+		{"method any", "DELETE", "/baz", true, 200, ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tbl.MethodNotAllowed = nil
+			if c.notAllowed {
+				tbl.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+				})
+			}
+
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+			if w.Code != c.wantCode {
+				t.Fatalf("want %v but got %v", c.wantCode, w.Code)
+			}
+			if allow := w.Header().Get("Allow"); allow != c.wantAllow {
+				t.Fatalf("want Allow %q but got %q", c.wantAllow, allow)
+			}
+		})
+	}
+}
+
+func TestStatusHandlers(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	for _, c := range []struct {
+		name     string
+		handler  interface{}
+		onError  func(w http.ResponseWriter, r *http.Request, status int, err error)
+		wantCode int
+		wantBody string
+	}{
+		{
+			"created",
+			func(w http.ResponseWriter, r *http.Request, id string) (int, error) {
+				return http.StatusCreated, nil
+			},
+			nil,
+			201,
+			"",
+		},
+		{
+			"zero status writes nothing",
+			func(w http.ResponseWriter, r *http.Request, id string) (int, error) {
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = fmt.Fprint(w, id)
+				return 0, nil
+			},
+			nil,
+			202,
+			"123",
+		},
+		{
+			"error",
+			func(w http.ResponseWriter, r *http.Request, id string) (int, error) {
+				return 0, errBoom
+			},
+			nil,
+			500,
+			"Internal Server Error\n",
+		},
+		{
+			"error with status",
+			func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars [1]string) (int, error) {
+				return http.StatusConflict, errBoom
+			},
+			nil,
+			409,
+			"Conflict\n",
+		},
+		{
+			"error handler",
+			func(w http.ResponseWriter, r *http.Request, id string) (int, error) {
+				return 0, errBoom
+			},
+			func(w http.ResponseWriter, r *http.Request, status int, err error) {
+				if !errors.Is(err, errBoom) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				w.WriteHeader(http.StatusTeapot)
+				_, _ = fmt.Fprintf(w, "%d %v", status, err)
+			},
+			418,
+			"0 boom",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			tbl := rte.Must(rte.Routes("POST /foo/:id", c.handler))
+			tbl.OnError = c.onError
+
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("POST", "/foo/123", nil))
+			if w.Code != c.wantCode {
+				t.Fatalf("want %v but got %v", c.wantCode, w.Code)
+			}
+			if body := w.Body.String(); body != c.wantBody {
+				t.Fatalf("want body %q but got %q", c.wantBody, body)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	echo := func(w http.ResponseWriter, r *http.Request, id, format string) {
+		_, _ = fmt.Fprintf(w, "id=%v format=%v", id, format)
+	}
+	tbl := rte.Must(rte.Routes(
+		"GET /posts/:id.:format", echo,
+		"GET /posts/:id/comments", func(w http.ResponseWriter, r *http.Request, id string) {
+			_, _ = fmt.Fprintf(w, "comments id=%v", id)
+		},
+		"GET /archive/:year/:slug.:ext", func(w http.ResponseWriter, r *http.Request, vars [3]string) {
+			_, _ = fmt.Fprintf(w, "%q", vars)
+		},
+	))
+	tbl.UnescapeVars = true
+
+	for _, c := range []struct{ path, want string }{
+		{"/posts/1.json", "id=1 format=json"},
+		{"/posts/1", "id=1 format="},
+		{"/posts/1.2.xml", "id=1.2 format=xml"},
+		{"/posts/1%2E2", "id=1.2 format="},
+		{"/posts/1/comments", "comments id=1"},
+		{"/archive/2020/hello.md", `["2020" "hello" "md"]`},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if body := w.Body.String(); body != c.want {
+				t.Fatalf("want %q but got %q", c.want, body)
+			}
+		})
+	}
+
+	t.Run("match", func(t *testing.T) {
+		m, ok := tbl.Match(httptest.NewRequest("GET", "/posts/1.json", nil))
+		if !ok || m.Route.Path != "/posts/:id.:format" || !reflect.DeepEqual(m.Vars, []string{"1", "json"}) {
+			t.Fatalf("unexpected match %+v", m)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, path := range []string{"/posts/:id.:", "/posts/.:format", "/posts/:.:format", "/posts/:id.:a.b", "/posts/:id.:f:g"} {
+			if _, err := rte.New(rte.Routes("GET "+path, echo)); err == nil {
+				t.Fatalf("expected an error for %q", path)
+			}
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	errMissing := errors.New("missing X-Token")
+
+	var called bool
+	tbl := rte.Must([]rte.Route{{
+		Method: "GET",
+		Path:   "/foo/:id",
+		Handler: func(w http.ResponseWriter, r *http.Request, id string) {
+			called = true
+		},
+		Validate: func(r *http.Request) error {
+			if r.Header.Get("X-Token") == "" {
+				return errMissing
+			}
+			return nil
+		},
+	}})
+
+	for _, c := range []struct {
+		name       string
+		token      string
+		onError    func(w http.ResponseWriter, r *http.Request, status int, err error)
+		wantCalled bool
+		wantCode   int
+		wantBody   string
+	}{
+		{"passing", "abc", nil, true, 200, ""},
+		{"failing", "", nil, false, 400, "Bad Request\n"},
+		{
+			"failing with error handler",
+			"",
+			func(w http.ResponseWriter, r *http.Request, status int, err error) {
+				http.Error(w, err.Error(), status)
+			},
+			false,
+			400,
+			"missing X-Token\n",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			called = false
+			tbl.OnError = c.onError
+
+			r := httptest.NewRequest("GET", "/foo/123", nil)
+			if c.token != "" {
+				r.Header.Set("X-Token", c.token)
+			}
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, r)
+
+			if called != c.wantCalled {
+				t.Fatalf("want called %v but got %v", c.wantCalled, called)
+			}
+			if w.Code != c.wantCode {
+				t.Fatalf("want %v but got %v", c.wantCode, w.Code)
+			}
+			if body := w.Body.String(); body != c.wantBody {
+				t.Fatalf("want body %q but got %q", c.wantBody, body)
+			}
+		})
+	}
+}
+
+func TestVarLengths(t *testing.T) {
+	echo := func(name string) func(w http.ResponseWriter, r *http.Request, v string) {
+		return func(w http.ResponseWriter, r *http.Request, v string) {
+			_, _ = fmt.Fprintf(w, "%v=%v", name, v)
+		}
+	}
+	tbl := rte.Must(rte.Routes(
+		"GET /s/:code{6}", echo("code"),
+		"GET /r/:code{4,8}", echo("code"),
+		"GET /r/:code{4,8}/stats", echo("stats"),
+		"GET /b/ab", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprint(w, "static")
+		},
+		rte.Route{Method: "GET", Path: "/b/:code{3}", Handler: echo("code"), Priority: 1},
+		"GET /p/:id{1,3}.:format", func(w http.ResponseWriter, r *http.Request, id, format string) {
+			_, _ = fmt.Fprintf(w, "id=%v format=%v", id, format)
+		},
+	))
+
+	for _, c := range []struct {
+		path, want string
+	}{
+		{"/s/abc123", "code=abc123"},
+		{"/s/abc12", "404 page not found\n"},
+		{"/s/abc1234", "404 page not found\n"},
+		{"/r/abcd", "code=abcd"},
+		{"/r/abcdefgh", "code=abcdefgh"},
+		{"/r/abc", "404 page not found\n"},
+		{"/r/abcdefghi", "404 page not found\n"},
+		{"/r/abcde/stats", "stats=abcde"},
+		{"/r/abc/stats", "404 page not found\n"},
+		{"/b/abc", "code=abc"},
+		{"/b/ab", "static"},
+		{"/p/1.json", "id=1 format=json"},
+		{"/p/123.json", "id=123 format=json"},
+		{"/p/123", "id=123 format="},
+		{"/p/1234.json", "404 page not found\n"},
+		{"/p/.json", "404 page not found\n"},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if body := w.Body.String(); body != c.want {
+				t.Fatalf("want %q but got %q", c.want, body)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, path := range []string{"/s/:code{}", "/s/:code{0}", "/s/:code{8,4}", "/s/:code{a}", "/s/:code{6", "/s/:{6}"} {
+			_, err := rte.New(rte.Routes("GET "+path, echo("code")))
+			if err == nil || err.(*rte.TableError).Type != rte.ErrTypeInvalidSegment {
+				t.Fatalf("expected an invalid segment error for %q but got %v", path, err)
+			}
+		}
+	})
+
+	t.Run("conflicting", func(t *testing.T) {
+		_, err := rte.New(rte.Routes(
+			"GET /s/:code{6}", echo("code"),
+			"POST /s/:code{4}", echo("code"),
+		))
+		if err == nil || err.(*rte.TableError).Type != rte.ErrTypeConflictingRoutes {
+			t.Fatalf("expected a conflicting routes error but got %v", err)
+		}
+	})
+}
+
+func TestContentType(t *testing.T) {
+	tbl := rte.Must([]rte.Route{
+		{
+			Method:      "GET",
+			Path:        "/json",
+			ContentType: "application/json",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprint(w, "{}")
+			},
+		},
+		{
+			Method:      "GET",
+			Path:        "/csv",
+			ContentType: "application/json",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/csv")
+				_, _ = fmt.Fprint(w, "a,b")
+			},
+		},
+		{
+			Method: "GET",
+			Path:   "/unset",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprint(w, "<p>hi</p>")
+			},
+		},
+	})
+
+	for _, c := range []struct{ path, want string }{
+		{"/json", "application/json"},
+		{"/csv", "text/csv"},
+		{"/unset", "text/html; charset=utf-8"},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tbl.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if ct := w.Header().Get("Content-Type"); ct != c.want {
+				t.Fatalf("want %q but got %q", c.want, ct)
+			}
+		})
+	}
+}
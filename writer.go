@@ -0,0 +1,126 @@
+package rte
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// errNotHijacker is returned when hijacking a connection whose ResponseWriter doesn't support it
+var errNotHijacker = errors.New("rte: the ResponseWriter does not implement http.Hijacker")
+
+// flush flushes w if it supports flushing; the wrappers below implement http.Flusher unconditionally so that it
+// isn't hidden from handlers, e.g. for server-sent events
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// hijack hijacks w's connection if it supports hijacking, e.g. for websockets
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errNotHijacker
+}
+
+// trackingWriter wraps a ResponseWriter, recording whether a response has been started
+type trackingWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *trackingWriter) WriteHeader(statusCode int) {
+	// informational responses, e.g. 103 Early Hints, precede the response proper
+	if statusCode >= 200 {
+		w.written = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *trackingWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *trackingWriter) Flush() {
+	// flushing sends the headers
+	w.written = true
+	flush(w.ResponseWriter)
+}
+
+func (w *trackingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijack(w.ResponseWriter)
+	if err == nil {
+		// the handler has taken over the connection, so nothing more can be written to it
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped ResponseWriter
+func (w *trackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// headWriter wraps a ResponseWriter, discarding the body so that a GET handler can serve a HEAD request
+type headWriter struct {
+	http.ResponseWriter
+}
+
+func (w headWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w headWriter) Flush() {
+	flush(w.ResponseWriter)
+}
+
+func (w headWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(w.ResponseWriter)
+}
+
+// Unwrap returns the wrapped ResponseWriter
+func (w headWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusWriter wraps a ResponseWriter, recording the status of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	// informational responses precede the final status
+	if w.status == 0 && statusCode >= 200 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	// flushing sends the headers with an implicit 200, as writing does
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	flush(w.ResponseWriter)
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(w.ResponseWriter)
+}
+
+// Unwrap returns the wrapped ResponseWriter
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}